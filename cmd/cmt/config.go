@@ -0,0 +1,330 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/dakoctba/cmt/internal/config"
+	"github.com/dakoctba/cmt/internal/git"
+	"github.com/spf13/cobra"
+)
+
+func newConfigCmd() *cobra.Command {
+	var global, repo bool
+
+	// loadErr is kept rather than returned so that an invalid config file
+	// can still be inspected, validated and fixed
+	var loadErr error
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect and change the configuration",
+		Long: `Inspect and change the configuration.
+
+Settings are layered, each layer overriding the previous one:
+
+  1. built-in defaults
+  2. the global config file: ~/.cmt.yaml, then ~/.config/cmt/config.yaml
+     (or the file given with --config)
+  3. .cmt.yaml at the root of the current repository
+  4. CMT_* environment variables, e.g. CMT_MODEL=qwen2.5-coder
+  5. command-line flags
+
+get, set, unset, list, edit and path work on the global config file unless
+--repo is given. Run "cmt config keys" for the list of keys.`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			_, loadErr = config.Load(cfgFile)
+			return nil
+		},
+	}
+	cmd.PersistentFlags().BoolVar(&global, "global", false, "use the global config file")
+	cmd.PersistentFlags().BoolVar(&repo, "repo", false, "use the repository's .cmt.yaml")
+	cmd.MarkFlagsMutuallyExclusive("global", "repo")
+
+	scope := func() config.Scope {
+		if repo {
+			return config.ScopeRepo
+		}
+		return config.ScopeGlobal
+	}
+	scopePath := func() (string, error) {
+		if scope() == config.ScopeGlobal && cfgFile != "" {
+			return cfgFile, nil
+		}
+		return config.ScopePath(scope())
+	}
+	explicit := func() bool {
+		return global || repo
+	}
+
+	var origin bool
+	show := &cobra.Command{
+		Use:   "show",
+		Short: "Print the effective configuration",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if loadErr != nil {
+				return loadErr
+			}
+			return showSettings(cmd.OutOrStdout(), config.Settings(), origin)
+		},
+	}
+	show.Flags().BoolVar(&origin, "origin", false, "show where each value comes from")
+	cmd.AddCommand(show)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get <key>",
+		Short: "Print a value: the effective one, or the one set in --global or --repo",
+		Example: `  cmt config get model
+  cmt config get --repo diff_budget
+  cmt config get models.llama3.1.diff_budget`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !explicit() {
+				if loadErr != nil {
+					return loadErr
+				}
+				for _, s := range config.Settings() {
+					if s.Key == args[0] {
+						fmt.Fprintln(cmd.OutOrStdout(), formatValue(s.Value))
+						return nil
+					}
+				}
+				return fmt.Errorf("%s is not set", args[0])
+			}
+
+			path, err := scopePath()
+			if err != nil {
+				return err
+			}
+			value, ok, err := config.Get(path, args[0])
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("%s is not set in %s", args[0], path)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), formatValue(value))
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a value in the global config, or in the repository's with --repo",
+		Long: `Set a value in the global config, or in the repository's with --repo.
+
+The value is checked against the key's type. Lists are given comma-separated.
+Comments and layout of the file are kept.`,
+		Example: `  cmt config set model qwen2.5-coder:7b
+  cmt config set --repo diff_strategy summarize
+  cmt config set ignore "*.snap,docs/api/*.md"
+  cmt config set models.llama3.1.diff_budget 6000`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := scopePath()
+			if err != nil {
+				return err
+			}
+			return config.Set(path, args[0], args[1], scope())
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "unset <key>",
+		Short: "Remove a value from the global config, or from the repository's with --repo",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := scopePath()
+			if err != nil {
+				return err
+			}
+			ok, err := config.Unset(path, args[0])
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("%s is not set in %s", args[0], path)
+			}
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the values set in the global config, or in the repository's with --repo",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := scopePath()
+			if err != nil {
+				return err
+			}
+			settings, err := config.FileSettings(path)
+			if err != nil {
+				return err
+			}
+			return showSettings(cmd.OutOrStdout(), settings, false)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "keys",
+		Short: "List the configuration keys with their types and defaults",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showKeys(cmd.OutOrStdout())
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "edit",
+		Short: "Open the global config, or the repository's with --repo, in the editor",
+		Long: `Open the global config, or the repository's with --repo, in the editor.
+
+The editor is the one git uses. The file is validated once the editor exits.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := scopePath()
+			if err != nil {
+				return err
+			}
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					return err
+				}
+				if err := os.WriteFile(path, nil, 0644); err != nil {
+					return err
+				}
+			}
+			if err := git.EditFile(cmd.Context(), path); err != nil {
+				return err
+			}
+			return validateFiles(cmd.OutOrStdout(), []configFile{{path, scope()}})
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Check the config files for unknown keys and invalid values",
+		Long: `Check the config files for unknown keys and invalid values.
+
+Without --global or --repo, every config file that applies here is checked.
+Problems are reported as file:line:column: message.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var files []configFile
+			if explicit() {
+				path, err := scopePath()
+				if err != nil {
+					return err
+				}
+				files = append(files, configFile{path, scope()})
+			} else {
+				globals := []string{cfgFile}
+				if cfgFile == "" {
+					var err error
+					if globals, err = config.GlobalFiles(); err != nil {
+						return err
+					}
+				}
+				for _, path := range globals {
+					files = append(files, configFile{path, config.ScopeGlobal})
+				}
+				if path := config.RepoFile(); path != "" {
+					files = append(files, configFile{path, config.ScopeRepo})
+				}
+			}
+			return validateFiles(cmd.OutOrStdout(), files)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "path",
+		Short: "Print the path of the global config file, or of the repository's with --repo",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := scopePath()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), path)
+			return nil
+		},
+	})
+
+	return cmd
+}
+
+// showSettings prints one key per line, aligned, optionally followed by the
+// layer that set it
+func showSettings(out io.Writer, settings []config.Setting, origin bool) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, s := range settings {
+		if origin {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, formatValue(s.Value), s.Origin)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\n", s.Key, formatValue(s.Value))
+		}
+	}
+	return w.Flush()
+}
+
+// showKeys prints the schema: key, accepted values, default and description
+func showKeys(out io.Writer) error {
+	defaults := map[string]any{}
+	for _, s := range config.Settings() {
+		if s.Origin == config.OriginDefault {
+			defaults[s.Key] = s.Value
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, k := range config.Keys() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", k.Name, k.TypeName(), formatValue(defaults[k.Name]), k.Doc)
+		for _, n := range k.Nested {
+			fmt.Fprintf(w, "%s.<name>.%s\t%s\t\t%s\n", k.Name, n.Name, n.TypeName(), n.Doc)
+		}
+	}
+	return w.Flush()
+}
+
+// configFile is a config file to validate
+type configFile struct {
+	path  string
+	scope config.Scope
+}
+
+// validateFiles reports the problems in the given config files, returning
+// an error when there are any
+func validateFiles(out io.Writer, files []configFile) error {
+	count, checked := 0, 0
+	for _, f := range files {
+		if _, err := os.Stat(f.path); os.IsNotExist(err) {
+			continue
+		}
+		checked++
+		problems, err := config.ValidateFile(f.path, f.scope)
+		if err != nil {
+			return err
+		}
+		for _, p := range problems {
+			fmt.Fprintf(out, "✖ %s\n", p)
+		}
+		count += len(problems)
+	}
+	if count > 0 {
+		return fmt.Errorf("%d problem(s) found in the configuration", count)
+	}
+	fmt.Fprintf(out, "✔ %d config file(s) checked\n", checked)
+	return nil
+}
+
+func formatValue(value any) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dakoctba/cmt/internal/config"
+	"github.com/dakoctba/cmt/internal/doctor"
+	"github.com/spf13/cobra"
+)
+
+func newDoctorCmd() *cobra.Command {
+	var asJSON bool
+
+	// loadErr is kept rather than returned so that a broken config file is
+	// reported as a failed check
+	var loadErr error
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that cmt is ready to write commit messages",
+		Long: `Check that cmt is ready to write commit messages.
+
+doctor checks the configuration, the git repository and its staged changes,
+the prepare-commit-msg hook, the model server and model, and measures how
+long the model takes to answer a tiny prompt. Each check passes, warns or
+fails with a hint on how to fix it; checks depending on a failed one are
+skipped. The exit status is 1 when a check failed.`,
+		Example: `  cmt doctor
+  cmt doctor --json | jq -e .ok`,
+		Args: cobra.NoArgs,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			_, loadErr = config.Load(cfgFile)
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report := doctor.Run(cmd.Context(), cfgFile, loadErr)
+			if err := cmd.Context().Err(); err != nil {
+				return err
+			}
+
+			if asJSON {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(report); err != nil {
+					return err
+				}
+			} else {
+				showReport(cmd.OutOrStdout(), report)
+			}
+			if failed := report.Failed(); len(failed) > 0 {
+				return fmt.Errorf("%d check(s) failed: %s", len(failed), strings.Join(failed, ", "))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the checks as JSON")
+
+	return cmd
+}
+
+// statusLabels are how check outcomes are shown to people
+var statusLabels = map[doctor.Status]string{
+	doctor.Pass: "✔",
+	doctor.Warn: "⚠",
+	doctor.Fail: "✖",
+	doctor.Skip: "-",
+}
+
+// showReport prints the checks for people
+func showReport(out io.Writer, r doctor.Report) {
+	for _, c := range r.Checks {
+		fmt.Fprintf(out, "%s %s: %s\n", statusLabels[c.Status], c.Name, c.Message)
+		if c.Hint != "" {
+			fmt.Fprintf(out, "    → %s\n", c.Hint)
+		}
+	}
+	if r.OK {
+		fmt.Fprintln(out, "\nEverything cmt needs is in place.")
+	}
+}
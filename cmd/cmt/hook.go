@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dakoctba/cmt/internal/commit"
+	"github.com/dakoctba/cmt/internal/hook"
+	"github.com/spf13/cobra"
+)
+
+func newHookCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hook",
+		Short: "Manage the git prepare-commit-msg hook",
+		Long: `Manage the git prepare-commit-msg hook.
+
+Once installed, "git commit" opens the editor with a generated message and the
+staged diff commented out below it. Commits that already have a message (-m,
+-F, merges, squashes and amends) are left untouched, and the commit is never
+blocked when cmt fails.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "install",
+		Short: "Install the prepare-commit-msg hook in the current repository",
+		Long: `Install the prepare-commit-msg hook in the current repository.
+
+The hook is written to the directory git runs hooks from, honouring
+core.hooksPath. An existing prepare-commit-msg hook is kept and run first.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			executable, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("failed to locate cmt executable: %v", err)
+			}
+			if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+				executable = resolved
+			}
+
+			path, err := hook.Install(cmd.Context(), executable)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Installed %s\n", path)
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove the prepare-commit-msg hook and restore any previous hook",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := hook.Uninstall(cmd.Context())
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed %s\n", path)
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "run <message-file> [source [commit]]",
+		Short: "Generate the message from within the prepare-commit-msg hook",
+		Long: `Generate the message from within the prepare-commit-msg hook.
+
+This is what the installed hook calls, with the arguments git passes to
+prepare-commit-msg: the message file, the message source and the commit id.`,
+		Args: cobra.RangeArgs(1, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source := ""
+			if len(args) > 1 {
+				source = args[1]
+			}
+			return commit.RunHook(cmd.Context(), args[0], source)
+		},
+	})
+
+	return cmd
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dakoctba/cmt/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func newInitCmd() *cobra.Command {
+	var repo, force bool
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Create a starter config file",
+		Long: `Create a starter config file.
+
+The global config file is written to ~/.config/cmt/config.yaml
+($XDG_CONFIG_HOME/cmt/config.yaml), or to the file given with --config. With
+--repo, a .cmt.yaml is written at the root of the current repository instead.
+An existing file is left alone unless --force is given.`,
+		Args: cobra.NoArgs,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// There is nothing to load yet
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := cfgFile
+			if repo || path == "" {
+				scope := config.ScopeGlobal
+				if repo {
+					scope = config.ScopeRepo
+				}
+				var err error
+				if path, err = config.ScopePath(scope); err != nil {
+					return err
+				}
+			}
+
+			if err := config.CreateFile(path, force); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Created config file: %s\n", path)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&repo, "repo", false, "create the repository's .cmt.yaml")
+	cmd.Flags().BoolVar(&force, "force", false, "replace an existing config file")
+
+	return cmd
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dakoctba/cmt/internal/git"
+	"github.com/dakoctba/cmt/internal/validator"
+	"github.com/spf13/cobra"
+)
+
+func newLintCmd() *cobra.Command {
+	var (
+		text string
+		file string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "lint [revision-range]",
+		Short: "Check commit messages against the Conventional Commits rules",
+		Long: `Check commit messages against the Conventional Commits rules.
+
+Rules are read from the repository's commitlint configuration (.commitlintrc,
+.commitlintrc.json/.yaml/.yml or commitlint.config.json/.yaml/.yml) and default
+to @commitlint/config-conventional.
+
+Without arguments the latest commit is checked. Pass a revision range such as
+main..HEAD to check several commits, --message to check a message directly, or
+--file to check a message file ("-" reads standard input), e.g. from a
+commit-msg hook.`,
+		Example: `  cmt lint
+  cmt lint origin/main..HEAD
+  cmt lint -m "feat(api): add login endpoint"
+  cmt lint --file .git/COMMIT_EDITMSG`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := git.RepoRoot(cmd.Context())
+			if err != nil {
+				return err
+			}
+			rules, _, err := validator.LoadRules(root)
+			if err != nil {
+				return err
+			}
+
+			var commits []git.LoggedCommit
+			switch {
+			case text != "":
+				commits = []git.LoggedCommit{{Message: text}}
+			case file != "":
+				content, err := readMessageFile(file)
+				if err != nil {
+					return err
+				}
+				commits = []git.LoggedCommit{{Message: git.StripComments(content)}}
+			case len(args) == 1:
+				if commits, err = git.Log(cmd.Context(), args[0], 0); err != nil {
+					return err
+				}
+			default:
+				if commits, err = git.Log(cmd.Context(), "HEAD", 1); err != nil {
+					return err
+				}
+			}
+
+			return lintMessages(cmd.OutOrStdout(), rules, commits)
+		},
+	}
+
+	cmd.Flags().StringVarP(&text, "message", "m", "", "commit message to check")
+	cmd.Flags().StringVarP(&file, "file", "f", "", `file containing the commit message to check ("-" for standard input)`)
+	return cmd
+}
+
+func readMessageFile(path string) (string, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit message: %v", err)
+	}
+	return string(data), nil
+}
+
+// lintMessages prints the violations of each message and fails when any
+// message breaks an error-level rule
+func lintMessages(out io.Writer, rules validator.Rules, commits []git.LoggedCommit) error {
+	invalid := 0
+	for _, c := range commits {
+		result := rules.Validate(c.Message)
+		if len(result.Violations) == 0 {
+			continue
+		}
+		if !result.Valid() {
+			invalid++
+		}
+
+		header, _, _ := strings.Cut(c.Message, "\n")
+		if c.Hash != "" {
+			header = c.Hash[:min(len(c.Hash), 7)] + " " + header
+		}
+		fmt.Fprintf(out, "%s\n", header)
+		for _, v := range result.Violations {
+			fmt.Fprintf(out, "    %s %s\n", v.Level.Symbol(), v)
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d commit message(s) failed validation", invalid, len(commits))
+	}
+	fmt.Fprintf(out, "✔ %d commit message(s) checked\n", len(commits))
+	return nil
+}
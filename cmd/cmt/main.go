@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/dakoctba/cmt/internal/commit"
 	"github.com/dakoctba/cmt/internal/config"
+	"github.com/dakoctba/cmt/internal/ui"
 	"github.com/spf13/cobra"
 )
 
@@ -16,32 +19,133 @@ var (
 	gitCommit = "unknown"
 
 	// Command flags
-	cfgFile string
-	model   string
+	cfgFile  string
+	model    string
+	noStream bool
+	gpgSign  string
 )
 
+// defaultSignKey is the -S flag value when no key id is given
+const defaultSignKey = "default"
+
+// exitInterrupted is the exit status after Ctrl-C, 128 + SIGINT as in
+// shells
+const exitInterrupted = 130
+
 func main() {
+	os.Exit(run())
+}
+
+// run executes the command line and returns the exit status. Ctrl-C
+// cancels the command's context, so that model requests and git commands
+// stop and the spinner clears its line before cmt exits. The editor
+// handles Ctrl-C itself, see git.EditFile.
+func run() int {
+	ctx, stop := ui.NotifyInterrupt(context.Background())
+	defer stop()
+	go func() {
+		// A second Ctrl-C kills cmt right away
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := newRootCmd().ExecuteContext(ctx)
+	switch {
+	case err == nil:
+		return 0
+	case ctx.Err() != nil:
+		fmt.Fprintln(os.Stderr, "\nInterrupted.")
+		return exitInterrupted
+	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	return 1
+}
+
+func newRootCmd() *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:   "cmt",
 		Short: "Generate conventional commit messages using AI",
 		Long: `cmt is a tool that generates conventional commit messages from staged Git changes using AI models.
 
 It analyzes your staged changes and generates a commit message following the Conventional Commits specification.`,
-		Version:       version,
+		Version: version,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			_, err := config.Load(cfgFile)
+			return err
+		},
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if noStream {
+				config.Override("stream", false)
+			}
+			if cmd.Flags().Changed("gpg-sign") {
+				config.Override("gpg_sign", true)
+				if gpgSign != defaultSignKey {
+					config.Override("gpg_key", gpgSign)
+				}
+			}
+		},
 		RunE:          commit.RunCommit,
 		SilenceUsage:  true, // Don't show usage on error
 		SilenceErrors: true, // Don't show error messages automatically
 	}
 
 	// Global flags
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.cmt)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ~/.config/cmt/config.yaml)")
 	rootCmd.PersistentFlags().StringVar(&model, "model", "", "specify the model to use")
+	config.BindFlag("model", rootCmd.PersistentFlags().Lookup("model"))
 
-	// Initialize config
-	config.InitConfig(cfgFile, model)
-
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	// Generation flags
+	rootCmd.Flags().BoolVar(&noStream, "no-stream", false, "wait for the complete message instead of streaming tokens as they arrive")
+	rootCmd.Flags().Duration("timeout", config.DefaultTimeout, "longest a model request may take, 0 waits forever")
+	config.BindFlag("timeout", rootCmd.Flags().Lookup("timeout"))
+	rootCmd.Flags().Int("retries", 2, "times a request failing with a temporary server error is repeated")
+	config.BindFlag("retries", rootCmd.Flags().Lookup("retries"))
+	rootCmd.Flags().StringSlice("fallback-models", nil, "models tried in order when the model is missing or keeps failing")
+	config.BindFlag("fallback_models", rootCmd.Flags().Lookup("fallback-models"))
+	rootCmd.Flags().Int("candidates", 1, "generate this many messages and pick one of them")
+	config.BindFlag("candidates", rootCmd.Flags().Lookup("candidates"))
+	rootCmd.Flags().Float64("temperature", 0, "sampling temperature (default the model's)")
+	rootCmd.Flags().Float64("top-p", 0, "nucleus sampling probability mass")
+	rootCmd.Flags().Int("top-k", 0, "number of most likely tokens sampled from")
+	rootCmd.Flags().Int("seed", 0, "random seed, for reproducible messages")
+	rootCmd.Flags().Int("num-ctx", 0, "context window in tokens")
+	rootCmd.Flags().Int("num-predict", 0, "most tokens generated per request")
+	rootCmd.Flags().StringSlice("stop", nil, "sequence that ends generation (repeatable)")
+	rootCmd.Flags().String("keep-alive", "", "how long the model stays loaded after a request, e.g. 10m")
+	rootCmd.Flags().String("system", "", "system prompt sent before the commit prompt")
+	for _, key := range []string{"temperature", "top_p", "top_k", "seed", "num_ctx", "num_predict", "stop", "keep_alive", "system"} {
+		config.BindFlag(key, rootCmd.Flags().Lookup(strings.ReplaceAll(key, "_", "-")))
 	}
+
+	// Output flags
+	rootCmd.Flags().StringP("output", "o", "", "print the message instead of asking: text, json, shell or raw")
+	config.BindFlag("output", rootCmd.Flags().Lookup("output"))
+
+	// Redaction flags
+	rootCmd.Flags().Bool("show-redactions", false, "list the secrets and personal data masked before sending the diff")
+	rootCmd.Flags().Bool("block-secrets", false, "refuse to run when the staged changes contain secrets")
+	config.BindFlag("show_redactions", rootCmd.Flags().Lookup("show-redactions"))
+	config.BindFlag("block_secrets", rootCmd.Flags().Lookup("block-secrets"))
+
+	// Commit flags
+	rootCmd.Flags().Bool("commit", false, "commit with the generated message without asking")
+	rootCmd.Flags().BoolP("signoff", "s", false, "add a Signed-off-by trailer to the commit")
+	rootCmd.Flags().Bool("no-verify", false, "bypass the pre-commit and commit-msg hooks")
+	rootCmd.Flags().StringVarP(&gpgSign, "gpg-sign", "S", "", "GPG-sign the commit, optionally with the given key id")
+	rootCmd.Flags().Lookup("gpg-sign").NoOptDefVal = defaultSignKey
+	config.BindFlag("commit", rootCmd.Flags().Lookup("commit"))
+	config.BindFlag("signoff", rootCmd.Flags().Lookup("signoff"))
+	config.BindFlag("no_verify", rootCmd.Flags().Lookup("no-verify"))
+
+	// Subcommands
+	rootCmd.AddCommand(newLintCmd())
+	rootCmd.AddCommand(newHookCmd())
+	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newInitCmd())
+	rootCmd.AddCommand(newPromptCmd())
+	rootCmd.AddCommand(newStyleCmd())
+	rootCmd.AddCommand(newDoctorCmd())
+	rootCmd.AddCommand(newModelsCmd())
+
+	return rootCmd
 }
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/dakoctba/cmt/internal/commit"
+	"github.com/dakoctba/cmt/internal/git/gittest"
+	"github.com/dakoctba/cmt/internal/ollama/ollamatest"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -260,12 +267,16 @@ func TestCommandExecution(t *testing.T) {
 		{
 			name:    "should handle empty args",
 			args:    []string{},
-			wantErr: false, // May succeed if ollama is available and there are staged changes
+			wantErr: false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			ollamatest.NewServer(t)
+			dir := gittest.Chdir(t)
+			gittest.Stage(t, dir, "main.txt", "staged content")
+
 			// Create a new root command
 			rootCmd := &cobra.Command{
 				Use:   "cmt",
@@ -295,3 +306,275 @@ It analyzes your staged changes and generates a commit message following the Con
 		})
 	}
 }
+
+func TestLintCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			name:    "should accept a conventional message",
+			args:    []string{"-m", "feat(cli): add lint command"},
+			wantErr: false,
+		},
+		{
+			name:    "should reject a non-conventional message",
+			args:    []string{"-m", "Added lint command."},
+			wantErr: true,
+		},
+		{
+			name:    "should check the latest commit",
+			args:    []string{},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := gittest.Chdir(t)
+			gittest.Stage(t, dir, "lint.txt", "content")
+			gittest.Run(t, dir, "commit", "-q", "-m", "chore: initial commit")
+
+			cmd := newLintCmd()
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(io.Discard)
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+
+			err := cmd.Execute()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("lint error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigLoading(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "should apply the model flag",
+			args: []string{"--model", "phi3", "config", "get", "model"},
+			want: "phi3\n",
+		},
+		{
+			name: "should read the file given with --config",
+			args: []string{"--config", "custom.yaml", "config", "get", "model"},
+			want: "custom-model\n",
+		},
+		{
+			name: "should create a config file with init",
+			args: []string{"init"},
+			want: "Created config file: ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("XDG_CONFIG_HOME", "")
+			dir := gittest.Chdir(t)
+			os.WriteFile(filepath.Join(dir, "custom.yaml"), []byte("model: custom-model\n"), 0644)
+			defer viper.Reset()
+
+			var out bytes.Buffer
+			cmd := newRootCmd()
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(&out)
+
+			err := cmd.Execute()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.HasPrefix(out.String(), tt.want) {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerationFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want map[string]any
+	}{
+		{
+			name: "should pass generation flags to the model",
+			args: []string{"--temperature", "0.3", "--seed", "7", "--num-ctx", "8192", "--stop", "END", "-o", "text"},
+			want: map[string]any{"temperature": 0.3, "seed": float64(7), "num_ctx": float64(8192), "stop": []any{"END"}},
+		},
+		{
+			name: "should leave unset options to the model",
+			args: []string{"--model", "test-model", "-o", "text"},
+		},
+		{
+			name: "should run recommended models with their context window",
+			args: []string{"-o", "text"},
+			want: map[string]any{"num_ctx": float64(8192)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("XDG_CONFIG_HOME", "")
+			server := ollamatest.NewServer(t)
+			dir := gittest.Chdir(t)
+			gittest.Stage(t, dir, "main.txt", "staged content")
+			defer viper.Reset()
+
+			cmd := newRootCmd()
+			cmd.SetArgs(tt.args)
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			if len(server.Options) != 1 {
+				t.Fatalf("requests = %d, want 1", len(server.Options))
+			}
+			got := server.Options[0]
+			if len(got) != len(tt.want) {
+				t.Errorf("options = %v, want %v", got, tt.want)
+			}
+			for key, want := range tt.want {
+				if fmt.Sprint(got[key]) != fmt.Sprint(want) {
+					t.Errorf("options[%s] = %v, want %v", key, got[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestDoctorCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		models  []string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "should print the checks as JSON",
+			models: []string{"llama3.1:latest"},
+			args:   []string{"--json"},
+			want:   `"ok": true`,
+		},
+		{
+			name:    "should fail when a check fails",
+			models:  []string{"phi3:latest"},
+			want:    `cmt models pull llama3.1`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("XDG_CONFIG_HOME", filepath.Join(t.TempDir(), "config"))
+			server := ollamatest.NewServer(t)
+			server.Models = tt.models
+			gittest.Chdir(t)
+
+			var out bytes.Buffer
+			cmd := newDoctorCmd()
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(&out)
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+
+			err := cmd.Execute()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("doctor error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("doctor output = %q, want it to contain %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestModelsCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+		check   func(t *testing.T, server *ollamatest.Server, home string)
+	}{
+		{
+			name: "should list the models with their details",
+			args: []string{"list"},
+			want: []string{"llama3.1:latest", "4.7 GB", "8.0B", "Q4_K_M", "131072"},
+		},
+		{
+			name: "should pull a model",
+			args: []string{"pull", "qwen2.5-coder:7b"},
+			want: []string{"Pulled qwen2.5-coder:7b", "cmt models use qwen2.5-coder:7b"},
+			check: func(t *testing.T, server *ollamatest.Server, home string) {
+				if len(server.Pulls) != 1 || server.Models[len(server.Models)-1] != "qwen2.5-coder:7b" {
+					t.Errorf("pulls = %v, models = %v, want qwen2.5-coder:7b pulled", server.Pulls, server.Models)
+				}
+			},
+		},
+		{
+			name:    "should report models the registry does not have",
+			args:    []string{"pull", "nope"},
+			wantErr: true,
+		},
+		{
+			name: "should set the model in the global config",
+			args: []string{"use", "phi3"},
+			want: []string{"Model set to phi3", "cmt models pull phi3"},
+			check: func(t *testing.T, server *ollamatest.Server, home string) {
+				data, err := os.ReadFile(filepath.Join(home, ".config", "cmt", "config.yaml"))
+				if err != nil || string(data) != "model: phi3\n" {
+					t.Errorf("config file = %q, %v, want model: phi3", data, err)
+				}
+			},
+		},
+		{
+			name: "should mark the recommended models already pulled",
+			args: []string{"recommend"},
+			want: []string{"qwen2.5-coder:7b", "16384"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("XDG_CONFIG_HOME", "")
+			server := ollamatest.NewServer(t)
+			server.Models = []string{"llama3.1:latest"}
+			server.Registry = []string{"qwen2.5-coder:7b"}
+			server.ContextLength = 131072
+			gittest.Chdir(t)
+			defer viper.Reset()
+
+			var out bytes.Buffer
+			cmd := newRootCmd()
+			cmd.SetArgs(append([]string{"models"}, tt.args...))
+			cmd.SetOut(&out)
+
+			err := cmd.Execute()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("models error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output = %q, want it to contain %q", out.String(), want)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, server, home)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/dakoctba/cmt/internal/catalog"
+	"github.com/dakoctba/cmt/internal/commit"
+	"github.com/dakoctba/cmt/internal/config"
+	"github.com/dakoctba/cmt/internal/progress"
+	"github.com/dakoctba/cmt/internal/provider"
+	"github.com/spf13/cobra"
+)
+
+func newModelsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "models",
+		Short: "List, pull and choose the models cmt writes with",
+		Long: `List, pull and choose the models cmt writes with.
+
+Recommended models run with the context window shown by "cmt models
+recommend" unless num_ctx is set, and their diff budget grows with it.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the models on the server",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			llm, err := newProvider()
+			if err != nil {
+				return err
+			}
+			models, err := listModels(cmd.Context(), llm)
+			if err != nil {
+				return err
+			}
+			if len(models) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), `No models yet. Pick one with "cmt models recommend" and download it with "cmt models pull".`)
+				return nil
+			}
+			return showModels(cmd.OutOrStdout(), models, config.GetModel())
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "pull <name>",
+		Short: "Download a model to the server",
+		Example: `  cmt models pull qwen2.5-coder:7b
+  cmt models pull llama3.1`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			llm, err := newProvider()
+			if err != nil {
+				return err
+			}
+			if err := commit.PullModel(cmd.Context(), llm, args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Pulled %s\n", args[0])
+			if args[0] != config.GetModel() {
+				fmt.Fprintf(cmd.OutOrStdout(), "Write commit messages with it: cmt models use %s\n", args[0])
+			}
+			return nil
+		},
+	})
+
+	var repo bool
+	use := &cobra.Command{
+		Use:   "use <name>",
+		Short: "Write commit messages with a model, set in the global config or the repository's with --repo",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			scope := config.ScopeGlobal
+			if repo {
+				scope = config.ScopeRepo
+			}
+			path := cfgFile
+			if scope == config.ScopeRepo || path == "" {
+				var err error
+				if path, err = config.ScopePath(scope); err != nil {
+					return err
+				}
+			}
+			if err := config.Set(path, "model", args[0], scope); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Model set to %s in %s\n", args[0], path)
+
+			// Only a hint: the server may be down, or the model pulled later
+			if llm, err := newProvider(); err == nil {
+				if models, err := llm.ListModels(cmd.Context()); err == nil {
+					if _, ok := provider.FindModel(models, args[0]); !ok {
+						fmt.Fprintf(cmd.OutOrStdout(), "%s is not on the server yet: cmt models pull %s\n", args[0], args[0])
+					}
+				}
+			}
+			return nil
+		},
+	}
+	use.Flags().BoolVar(&repo, "repo", false, "set the model in the repository's .cmt.yaml")
+	cmd.AddCommand(use)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "recommend",
+		Short: "List models that write good commit messages",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Mark the models already pulled, when the server says
+			var models []provider.Model
+			if llm, err := newProvider(); err == nil {
+				models, _ = llm.ListModels(cmd.Context())
+			}
+			return showRecommended(cmd.OutOrStdout(), models)
+		},
+	})
+
+	return cmd
+}
+
+// newProvider connects to the configured model server
+func newProvider() (provider.Provider, error) {
+	return provider.New(config.GetProvider(), config.GetBaseURL(), config.GetAPIKey())
+}
+
+// listModels returns the server's models, with their context length when
+// the server can tell
+func listModels(ctx context.Context, llm provider.Provider) ([]provider.Model, error) {
+	models, err := llm.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if manager, ok := llm.(provider.Manager); ok {
+		for i, m := range models {
+			if details, err := manager.ShowModel(ctx, m.Name); err == nil {
+				models[i].ContextLength = details.ContextLength
+			}
+		}
+	}
+	return models, nil
+}
+
+// showModels prints the server's models, marking the configured one
+func showModels(out io.Writer, models []provider.Model, current string) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "\tNAME\tSIZE\tPARAMETERS\tQUANTIZATION\tCONTEXT")
+	for _, m := range models {
+		mark := ""
+		if _, ok := provider.FindModel([]provider.Model{m}, current); ok {
+			mark = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", mark, m.Name, orDash(formatSize(m.Size)), orDash(m.ParameterSize), orDash(m.Quantization), orDash(formatTokens(m.ContextLength)))
+	}
+	return w.Flush()
+}
+
+// showRecommended prints the catalog, marking the models already pulled
+func showRecommended(out io.Writer, pulled []provider.Model) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "\tNAME\tSIZE\tCONTEXT\tDESCRIPTION")
+	for _, e := range catalog.Recommended {
+		mark := ""
+		if _, ok := provider.FindModel(pulled, e.Name); ok {
+			mark = "✔"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", mark, e.Name, e.Size, formatTokens(e.Context), e.Description)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "\nCONTEXT is the window cmt runs the model with unless num_ctx is set.")
+	return nil
+}
+
+func formatSize(n int64) string {
+	if n <= 0 {
+		return ""
+	}
+	return progress.Bytes(n)
+}
+
+func formatTokens(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dakoctba/cmt/internal/commit"
+	"github.com/dakoctba/cmt/internal/config"
+	"github.com/dakoctba/cmt/internal/prompt"
+	"github.com/spf13/cobra"
+)
+
+func newPromptCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prompt",
+		Short: "Inspect the prompt sent to the model",
+		Long: `Inspect the prompt sent to the model.
+
+The commit prompt is a Go text/template, set with prompt_template either inline
+or as the path of a file. It is rendered with:
+
+  .Diff           the staged diff, or its summary when it is too large
+  .Files          the paths of the staged files
+  .Branch         the checked-out branch, empty when HEAD is detached
+  .Ticket         the ticket ID read from the branch name
+  .TypeHint       the commit type the branch name suggests
+  .RecentCommits  the subjects of the latest commits, newest first
+  .Types          the commit types allowed by the commitlint rules
+  .Scopes         the scopes of the staged files, most files first
+  .Scope          the scope to use, empty when it should be left out
+  .Language       the configured language
+  .Style          the commit style learned from the history, see "cmt style"
+
+Besides the text/template builtins, templates can use join (strings.Join) and
+describe, which returns the description of a commit type.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "show",
+		Short: "Print the prompt rendered for the staged changes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commit.ShowPrompt(cmd.Context(), cmd.OutOrStdout())
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "template",
+		Short: "Print the prompt template in use, a starting point for your own",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			text, err := config.GetPromptTemplate()
+			if err != nil {
+				return err
+			}
+			if text == "" {
+				text = prompt.DefaultTemplate
+			}
+			fmt.Fprint(cmd.OutOrStdout(), text)
+			return nil
+		},
+	})
+
+	return cmd
+}
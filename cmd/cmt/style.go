@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dakoctba/cmt/internal/config"
+	"github.com/dakoctba/cmt/internal/git"
+	"github.com/dakoctba/cmt/internal/style"
+	"github.com/spf13/cobra"
+)
+
+func newStyleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "style",
+		Short: "Inspect the commit style learned from the repository's history",
+		Long: `Inspect the commit style learned from the repository's history.
+
+cmt samples the latest style_commits commits of the current branch (default
+50, 0 turns learning off) and tells the model about the scopes in use and the
+style of the subjects and bodies, showing style_examples of the messages as
+examples.`,
+	}
+
+	var count int
+	var asJSON bool
+	analyze := &cobra.Command{
+		Use:   "analyze",
+		Short: "Print the conventions detected in the recent commits",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := git.CheckRepo(cmd.Context()); err != nil {
+				return err
+			}
+			if !cmd.Flags().Changed("count") {
+				if count = config.GetStyleCommits(); count <= 0 {
+					count = config.DefaultStyleCommits
+				}
+			}
+
+			s, err := style.Learn(cmd.Context(), count, config.GetStyleExamples())
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(s)
+			}
+			return showStyle(cmd.OutOrStdout(), s)
+		},
+	}
+	analyze.Flags().IntVarP(&count, "count", "n", 0, "number of recent commits to analyze (default style_commits)")
+	analyze.Flags().BoolVar(&asJSON, "json", false, "print the analysis as JSON")
+	cmd.AddCommand(analyze)
+
+	return cmd
+}
+
+// showStyle prints a style analysis for people
+func showStyle(out io.Writer, s style.Style) error {
+	if s.Commits == 0 {
+		fmt.Fprintln(out, "No commits to analyze.")
+		return nil
+	}
+
+	fmt.Fprintf(out, "Analyzed %d commit(s), %d following Conventional Commits\n", s.Commits, s.Conventional)
+	fmt.Fprintf(out, "Average subject length: %d characters\n", s.AverageSubjectLength)
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	if len(s.Types) > 0 {
+		fmt.Fprintln(w, "\nTypes:")
+		for _, c := range s.Types {
+			fmt.Fprintf(w, "  %s\t%d\t%d%%\n", c.Name, c.Count, c.Count*100/s.Conventional)
+		}
+	}
+	if len(s.Scopes) > 0 {
+		fmt.Fprintln(w, "\nScopes:")
+		for _, c := range s.Scopes {
+			fmt.Fprintf(w, "  %s\t%d\n", c.Name, c.Count)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "\nTraits:")
+	for _, trait := range s.Traits {
+		fmt.Fprintf(out, "  - %s\n", trait)
+	}
+	if len(s.Examples) > 0 {
+		fmt.Fprintln(out, "\nExamples:")
+		for _, example := range s.Examples {
+			fmt.Fprintf(out, "\n  %s\n", strings.ReplaceAll(example, "\n", "\n  "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Package branch reads ticket IDs and the kind of change from branch names
+// such as feature/PROJ-1234-add-login.
+package branch
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// DefaultPattern matches an optional "<type>/" prefix and a ticket ID such
+// as PROJ-1234 anywhere in the name
+const DefaultPattern = `^(?:(?P<type>[A-Za-z]+)/)?(?:(?:.*?[^A-Za-z0-9])?(?P<ticket>[A-Z][A-Z0-9]+-[0-9]+)(?:[^0-9]|$))?`
+
+// DefaultTypes map branch types to the commit type they suggest
+var DefaultTypes = map[string]string{
+	"feature":  "feat",
+	"feat":     "feat",
+	"bugfix":   "fix",
+	"fix":      "fix",
+	"hotfix":   "fix",
+	"docs":     "docs",
+	"refactor": "refactor",
+	"perf":     "perf",
+	"test":     "test",
+	"build":    "build",
+	"ci":       "ci",
+	"chore":    "chore",
+}
+
+// Info is what a branch name says about the change
+type Info struct {
+	// Name is the branch name
+	Name string
+
+	// Type and Ticket are the "type" and "ticket" groups of the pattern
+	Type   string
+	Ticket string
+
+	// Groups holds every named group of the pattern that matched
+	Groups map[string]string
+
+	// CommitType is the commit type suggested by Type, if any
+	CommitType string
+}
+
+// Parser extracts Info from branch names with a regular expression whose
+// named groups "type" and "ticket" are recognised
+type Parser struct {
+	pattern *regexp.Regexp
+	types   map[string]string
+}
+
+// New compiles a branch name pattern, DefaultPattern when empty. types map
+// branch types to commit types and are added to DefaultTypes.
+func New(pattern string, types map[string]string) (*Parser, error) {
+	if pattern == "" {
+		pattern = DefaultPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid branch pattern: %w", err)
+	}
+
+	p := &Parser{pattern: re, types: map[string]string{}}
+	for branchType, commitType := range DefaultTypes {
+		p.types[branchType] = commitType
+	}
+	for branchType, commitType := range types {
+		p.types[strings.ToLower(branchType)] = commitType
+	}
+	return p, nil
+}
+
+// Parse reads a branch name. An empty name, as with a detached HEAD, gives
+// an empty Info.
+func (p *Parser) Parse(name string) Info {
+	info := Info{Name: name, Groups: map[string]string{}}
+	if name == "" {
+		return info
+	}
+	match := p.pattern.FindStringSubmatch(name)
+	if match == nil {
+		return info
+	}
+	for i, group := range p.pattern.SubexpNames() {
+		if group != "" && match[i] != "" {
+			info.Groups[group] = match[i]
+		}
+	}
+	info.Type = info.Groups["type"]
+	info.Ticket = info.Groups["ticket"]
+	info.CommitType = p.types[strings.ToLower(info.Type)]
+	return info
+}
+
+// Render renders a text/template with the branch Info, e.g.
+// "Refs: {{.Ticket}}"
+func Render(text string, info Info) (string, error) {
+	tmpl, err := template.New("branch").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid branch template %q: %w", text, err)
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, info); err != nil {
+		return "", fmt.Errorf("failed to render branch template %q: %w", text, err)
+	}
+	return b.String(), nil
+}
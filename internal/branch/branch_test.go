@@ -0,0 +1,118 @@
+package branch
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name           string
+		pattern        string
+		types          map[string]string
+		branch         string
+		wantType       string
+		wantTicket     string
+		wantCommitType string
+	}{
+		{
+			name:           "should read the type and ticket",
+			branch:         "feature/PROJ-1234-add-login",
+			wantType:       "feature",
+			wantTicket:     "PROJ-1234",
+			wantCommitType: "feat",
+		},
+		{
+			name:           "should find the ticket after other words",
+			branch:         "bugfix/login-crash-APP-7",
+			wantType:       "bugfix",
+			wantTicket:     "APP-7",
+			wantCommitType: "fix",
+		},
+		{
+			name:       "should read a ticket without a type",
+			branch:     "PROJ-99_cleanup",
+			wantTicket: "PROJ-99",
+		},
+		{
+			name:     "should read a type without a ticket",
+			branch:   "docs/readme",
+			wantType: "docs", wantCommitType: "docs",
+		},
+		{
+			name:   "should handle a detached HEAD",
+			branch: "",
+		},
+		{
+			name:           "should use a custom pattern and types",
+			pattern:        `^(?P<user>[a-z]+)/(?P<type>[a-z]+)/(?P<ticket>[0-9]+)`,
+			types:          map[string]string{"Story": "feat"},
+			branch:         "ana/story/4521-search",
+			wantType:       "story",
+			wantTicket:     "4521",
+			wantCommitType: "feat",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.pattern, tt.types)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			info := p.Parse(tt.branch)
+			if info.Type != tt.wantType || info.Ticket != tt.wantTicket || info.CommitType != tt.wantCommitType {
+				t.Errorf("Parse(%q) = type %q, ticket %q, commit type %q, want %q, %q, %q",
+					tt.branch, info.Type, info.Ticket, info.CommitType, tt.wantType, tt.wantTicket, tt.wantCommitType)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	p, err := New(`^(?P<type>[a-z]+)/(?P<ticket>[A-Z]+-[0-9]+)-(?P<slug>.*)$`, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	info := p.Parse("feature/PROJ-1-add-login")
+
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "should render the ticket",
+			text: "Refs: {{.Ticket}}",
+			want: "Refs: PROJ-1",
+		},
+		{
+			name: "should render other groups",
+			text: "[{{.Ticket}}] {{.Groups.slug}}",
+			want: "[PROJ-1] add-login",
+		},
+		{
+			name:    "should reject invalid templates",
+			text:    "Refs: {{.Ticket",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.text, info)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewInvalidPattern(t *testing.T) {
+	if _, err := New(`(?P<ticket>[A-Z+`, nil); err == nil {
+		t.Error("New() should reject an invalid pattern")
+	}
+}
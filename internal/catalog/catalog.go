@@ -0,0 +1,62 @@
+// Package catalog lists local models that write good commit messages,
+// together with the context window cmt runs them with.
+package catalog
+
+import "strings"
+
+// Entry is a recommended model
+type Entry struct {
+	// Name is the model name to pull, with its tag
+	Name string
+
+	// Size is the approximate download size, e.g. "4.7 GB"
+	Size string
+
+	// Context is the context window in tokens cmt runs the model with: large
+	// enough for sizeable diffs, small enough for a laptop's memory. It is
+	// sent as num_ctx and sizes the diff budget unless they are configured.
+	Context int
+
+	// Description says what the model is good at
+	Description string
+}
+
+// Recommended are coding-friendly models, best suited first
+var Recommended = []Entry{
+	{Name: "qwen2.5-coder:7b", Size: "4.7 GB", Context: 16384, Description: "strong at reading code, the best default"},
+	{Name: "qwen2.5-coder:14b", Size: "9.0 GB", Context: 16384, Description: "more accurate, needs 16 GB of memory"},
+	{Name: "qwen2.5-coder:1.5b", Size: "986 MB", Context: 8192, Description: "fast on small machines, terser messages"},
+	{Name: "llama3.1:8b", Size: "4.9 GB", Context: 8192, Description: "good general-purpose writer"},
+	{Name: "llama3.2:3b", Size: "2.0 GB", Context: 8192, Description: "small and quick general-purpose model"},
+	{Name: "deepseek-coder-v2:16b", Size: "8.9 GB", Context: 16384, Description: "mixture of experts, fast for its size"},
+	{Name: "mistral:7b", Size: "4.1 GB", Context: 8192, Description: "concise messages"},
+	{Name: "gemma2:9b", Size: "5.4 GB", Context: 8192, Description: "careful wording, shorter context"},
+	{Name: "codellama:7b", Size: "3.8 GB", Context: 8192, Description: "older code model"},
+}
+
+// aliases are the names the registry also knows the recommended models by
+var aliases = map[string]string{
+	"qwen2.5-coder":     "qwen2.5-coder:7b",
+	"llama3.1":          "llama3.1:8b",
+	"llama3.2":          "llama3.2:3b",
+	"deepseek-coder-v2": "deepseek-coder-v2:16b",
+	"mistral":           "mistral:7b",
+	"gemma2":            "gemma2:9b",
+	"codellama":         "codellama:7b",
+}
+
+// Lookup finds a recommended model by name, case-insensitively. A name
+// without a tag, or with the "latest" tag, stands for the registry's
+// default size.
+func Lookup(name string) (Entry, bool) {
+	name = strings.TrimSuffix(strings.ToLower(name), ":latest")
+	if full, ok := aliases[name]; ok {
+		name = full
+	}
+	for _, e := range Recommended {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
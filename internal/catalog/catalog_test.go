@@ -0,0 +1,26 @@
+package catalog
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name  string
+		model string
+		want  string
+	}{
+		{name: "should find a model by its full name", model: "qwen2.5-coder:14b", want: "qwen2.5-coder:14b"},
+		{name: "should read a missing tag as the default size", model: "llama3.1", want: "llama3.1:8b"},
+		{name: "should read the latest tag as the default size", model: "Qwen2.5-Coder:latest", want: "qwen2.5-coder:7b"},
+		{name: "should not find other sizes", model: "llama3.1:70b"},
+		{name: "should not find unknown models", model: "phi3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, ok := Lookup(tt.model)
+			if ok != (tt.want != "") || e.Name != tt.want {
+				t.Errorf("Lookup(%q) = %q, %v, want %q", tt.model, e.Name, ok, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package commit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dakoctba/cmt/internal/branch"
+	"github.com/dakoctba/cmt/internal/config"
+	"github.com/dakoctba/cmt/internal/git"
+	"github.com/dakoctba/cmt/internal/message"
+)
+
+// readBranch parses the name of the current branch with the configured
+// pattern. A detached HEAD leaves g.branch empty.
+func (g *generator) readBranch() error {
+	parser, err := branch.New(config.GetBranchPattern(), config.GetBranchTypes())
+	if err != nil {
+		return err
+	}
+	name, err := git.CurrentBranch(g.ctx)
+	if err != nil {
+		return err
+	}
+	g.branch = parser.Parse(name)
+	return nil
+}
+
+// addTicket adds the ticket ID read from the branch name to the message,
+// as a subject prefix and a trailer rendered from the ticket_prefix and
+// ticket_footer templates. Nothing is added twice.
+func (g *generator) addTicket(msg *message.Message) error {
+	ticket := g.branch.Ticket
+	if ticket == "" {
+		return nil
+	}
+
+	if tmpl := config.GetTicketPrefix(); tmpl != "" {
+		prefix, err := branch.Render(tmpl, g.branch)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(msg.Subject, ticket) {
+			msg.Subject = prefix + msg.Subject
+		}
+	}
+
+	if tmpl := config.GetTicketFooter(); tmpl != "" {
+		text, err := branch.Render(tmpl, g.branch)
+		if err != nil {
+			return err
+		}
+		footer, ok := message.ParseFooter(text)
+		if !ok {
+			return fmt.Errorf("ticket_footer must render a trailer such as \"Refs: PROJ-1\", got %q", text)
+		}
+		if value, ok := msg.Footer(footer.Token); !ok || !strings.Contains(value, ticket) {
+			msg.Footers = append(msg.Footers, footer)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package commit
+
+import (
+	"testing"
+
+	"github.com/dakoctba/cmt/internal/branch"
+	"github.com/dakoctba/cmt/internal/message"
+	"github.com/spf13/viper"
+)
+
+func TestAddTicket(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]any
+		ticket   string
+		raw      string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:   "should add a Refs trailer",
+			ticket: "PROJ-1234",
+			raw:    "feat: add login",
+			want:   "feat: add login\n\nRefs: PROJ-1234",
+		},
+		{
+			name:   "should not repeat a trailer the model wrote",
+			ticket: "PROJ-1234",
+			raw:    "feat: add login\n\nRefs: PROJ-1234",
+			want:   "feat: add login\n\nRefs: PROJ-1234",
+		},
+		{
+			name:     "should add a subject prefix",
+			settings: map[string]any{"ticket_prefix": "{{.Ticket}} ", "ticket_footer": ""},
+			ticket:   "APP-7",
+			raw:      "fix: handle empty names",
+			want:     "fix: APP-7 handle empty names",
+		},
+		{
+			name:     "should not repeat a ticket the subject names",
+			settings: map[string]any{"ticket_prefix": "{{.Ticket}} ", "ticket_footer": ""},
+			ticket:   "APP-7",
+			raw:      "fix: handle empty names in APP-7",
+			want:     "fix: handle empty names in APP-7",
+		},
+		{
+			name: "should leave the message alone without a ticket",
+			raw:  "feat: add login",
+			want: "feat: add login",
+		},
+		{
+			name:     "should reject a footer that is not a trailer",
+			settings: map[string]any{"ticket_footer": "see {{.Ticket}}"},
+			ticket:   "PROJ-1234",
+			raw:      "feat: add login",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("ticket_footer", "Refs: {{.Ticket}}")
+			for key, value := range tt.settings {
+				viper.Set(key, value)
+			}
+			defer viper.Reset()
+
+			msg, err := message.Parse(tt.raw)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			g := &generator{branch: branch.Info{Ticket: tt.ticket}}
+			err = g.addTicket(msg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("addTicket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && msg.String() != tt.want {
+				t.Errorf("message = %q, want %q", msg.String(), tt.want)
+			}
+		})
+	}
+}
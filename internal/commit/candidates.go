@@ -0,0 +1,180 @@
+package commit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dakoctba/cmt/internal/message"
+	"github.com/dakoctba/cmt/internal/provider"
+	"github.com/dakoctba/cmt/internal/spinner"
+	"github.com/dakoctba/cmt/internal/ui"
+	"github.com/dakoctba/cmt/internal/validator"
+)
+
+// Sampling settings of the candidates: the temperature is spread evenly
+// from the configured one, or minTemperature, up to maxTemperature so that
+// the messages differ
+const (
+	minTemperature = 0.2
+	maxTemperature = 1.0
+
+	// maxParallel is the number of candidate requests in flight at once.
+	// Servers queue requests beyond what they run in parallel.
+	maxParallel = 4
+)
+
+// Candidate is a generated message with the rules it breaks
+type Candidate struct {
+	Message *message.Message
+	Result  validator.Result
+
+	// Raw is the model output the message was read from
+	Raw string
+}
+
+// MarshalJSON encodes the message fields together with the rendered
+// message and its violations
+func (c Candidate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		*message.Message
+		Header     string                `json:"header"`
+		Text       string                `json:"message"`
+		Score      int                   `json:"score"`
+		Violations []validator.Violation `json:"violations"`
+	}{c.Message, c.Message.Header(), c.Message.String(), c.Result.Score(), c.Result.Violations})
+}
+
+// candidateOptions returns the generation options of the i-th of n
+// candidates, varying the temperature and seed of the configured options.
+// The seeds of each round of candidates follow those of the round before,
+// so that regenerating asks for new messages.
+func candidateOptions(options provider.Options, i, n, round int) provider.Options {
+	low := minTemperature
+	if options.Temperature != nil {
+		low = *options.Temperature
+	}
+	temperature := low
+	if n > 1 && low < maxTemperature {
+		temperature += (maxTemperature - low) * float64(i) / float64(n-1)
+	}
+
+	seed := round*n + i + 1
+	if options.Seed != nil {
+		seed = *options.Seed + round*n + i
+	}
+	options.Temperature, options.Seed = &temperature, &seed
+	return options
+}
+
+// candidates asks the model for n messages at once with varied sampling
+// options. Duplicates are dropped and the rest ranked by their validator
+// score, best first. Failed requests are skipped unless all of them fail.
+func (g *generator) candidates(n int) ([]Candidate, error) {
+	request, err := g.request()
+	if err != nil {
+		return nil, err
+	}
+	round := g.rounds
+	g.rounds++
+
+	spinner := spinner.New()
+	spinner.StartMessage(fmt.Sprintf("Writing %d messages with %s model...", n, g.model))
+
+	var (
+		wg      sync.WaitGroup
+		slots   = make(chan struct{}, maxParallel)
+		answers = make([]string, n)
+		errs    = make([]error, n)
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			req := request
+			req.Options = candidateOptions(request.Options, i, n, round)
+			resp, err := g.send(req, nil)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			g.record(resp)
+			answers[i] = strings.TrimSpace(resp.Text)
+		}(i)
+	}
+	wg.Wait()
+	spinner.Stop()
+
+	var (
+		out     []Candidate
+		seen    = map[string]bool{}
+		lastErr error
+	)
+	for i, raw := range answers {
+		if errs[i] == nil {
+			var c Candidate
+			if c, errs[i] = g.check(raw); errs[i] == nil {
+				if text := c.Message.String(); !seen[text] {
+					seen[text] = true
+					out = append(out, c)
+				}
+				continue
+			}
+		}
+		lastErr = errs[i]
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("failed to generate commit message: %w", lastErr)
+	}
+	if lastErr != nil {
+		fmt.Fprintf(os.Stderr, "Some messages could not be generated: %v\n", lastErr)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Result.Score() < out[j].Result.Score()
+	})
+	return out, nil
+}
+
+// printCandidates writes the candidates as a JSON array
+func printCandidates(w io.Writer, candidates []Candidate) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(candidates)
+}
+
+// pickCandidate lists the numbered candidates and asks the user to pick
+// one. It reports whether the user asked for new candidates instead; a nil
+// candidate without regenerate means the commit was aborted.
+func pickCandidate(prompter *ui.Prompter, candidates []Candidate) (*Candidate, bool, error) {
+	for i, c := range candidates {
+		status := "passes the commit rules"
+		if errs := len(c.Result.Errors()); len(c.Result.Violations) > 0 {
+			status = fmt.Sprintf("%d error(s), %d warning(s)", errs, len(c.Result.Violations)-errs)
+		}
+		fmt.Printf("\n%d) %s\n   (%s)\n", i+1, strings.ReplaceAll(c.Message.String(), "\n", "\n   "), status)
+	}
+	fmt.Println()
+
+	i, key, err := prompter.Select("Which message?", len(candidates), []ui.Choice{
+		{Key: "r", Label: "regenerate"},
+		{Key: "q", Label: "abort"},
+	})
+	switch {
+	case err != nil:
+		return nil, false, err
+	case key == "r":
+		return nil, true, nil
+	case key != "":
+		fmt.Println("Commit aborted.")
+		return nil, false, nil
+	}
+	return &candidates[i], false, nil
+}
@@ -0,0 +1,118 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/dakoctba/cmt/internal/provider"
+	"github.com/dakoctba/cmt/internal/validator"
+	"github.com/spf13/viper"
+)
+
+// seedProvider records the seed of each request and answers with a message
+// naming it
+type seedProvider struct {
+	provider.Provider
+	mu    sync.Mutex
+	seeds []int
+}
+
+func (p *seedProvider) Generate(ctx context.Context, req provider.Request) (*provider.Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.seeds = append(p.seeds, *req.Options.Seed)
+	return &provider.Response{Model: req.Model, Text: fmt.Sprintf("feat: add feature %d", *req.Options.Seed)}, nil
+}
+
+func TestCandidateOptions(t *testing.T) {
+	temperature := func(v float64) *float64 { return &v }
+	seed := func(v int) *int { return &v }
+
+	tests := []struct {
+		name    string
+		options provider.Options
+		n       int
+		round   int
+		want    []string
+	}{
+		{
+			name: "should spread the temperature and seed by default",
+			n:    3,
+			want: []string{"0.20/1", "0.60/2", "1.00/3"},
+		},
+		{
+			name:    "should vary the configured temperature and seed",
+			options: provider.Options{Temperature: temperature(0.5), Seed: seed(42)},
+			n:       2,
+			want:    []string{"0.50/42", "1.00/43"},
+		},
+		{
+			name:  "should change the seeds when regenerating",
+			n:     2,
+			round: 1,
+			want:  []string{"0.20/3", "1.00/4"},
+		},
+		{
+			name:    "should change the configured seeds when regenerating",
+			options: provider.Options{Temperature: temperature(0.5), Seed: seed(42)},
+			n:       2,
+			round:   2,
+			want:    []string{"0.50/46", "1.00/47"},
+		},
+		{
+			name:    "should keep a temperature above the spread",
+			options: provider.Options{Temperature: temperature(1.2)},
+			n:       2,
+			want:    []string{"1.20/1", "1.20/2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for i := 0; i < tt.n; i++ {
+				options := candidateOptions(tt.options, i, tt.n, tt.round)
+				got = append(got, fmt.Sprintf("%.2f/%d", *options.Temperature, *options.Seed))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("temperature/seed = %v, want %v", got, tt.want)
+			}
+			if tt.options.Seed != nil && *tt.options.Seed != 42 {
+				t.Errorf("candidateOptions() changed the configured seed to %d", *tt.options.Seed)
+			}
+		})
+	}
+}
+
+func TestCandidatesRegenerate(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	llm := &seedProvider{}
+	g := &generator{
+		ctx:          context.Background(),
+		llm:          llm,
+		model:        "llama3.1",
+		rules:        validator.DefaultRules(),
+		commitPrompt: "Describe the change.",
+		chain:        []string{"llama3.1"},
+	}
+
+	seen := map[int]bool{}
+	for round := 0; round < 2; round++ {
+		llm.seeds = nil
+		if _, err := g.candidates(2); err != nil {
+			t.Fatalf("candidates() error = %v", err)
+		}
+		sort.Ints(llm.seeds)
+		for _, s := range llm.seeds {
+			if seen[s] {
+				t.Errorf("round %d seeds = %v, want seeds not sent before", round+1, llm.seeds)
+			}
+			seen[s] = true
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package commit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dakoctba/cmt/internal/config"
+	"github.com/dakoctba/cmt/internal/diff"
+	"github.com/dakoctba/cmt/internal/prompt"
+	"github.com/dakoctba/cmt/internal/redact"
+	"github.com/dakoctba/cmt/internal/spinner"
+)
+
+// describeChanges returns what the model is shown of the staged changes:
+// the diff itself when it fits in the model's budget, otherwise per-file
+// summaries or, as a last resort, an outline of the changed files. Files
+// left out by the ignore rules are listed by name after it.
+func (g *generator) describeChanges() (string, error) {
+	changes, err := g.describeFiles()
+	if err != nil {
+		return "", err
+	}
+	if note := diff.OmittedNote(g.omitted); note != "" {
+		changes = strings.TrimRight(changes, "\n") + "\n\n" + note
+	}
+	return strings.TrimLeft(changes, "\n"), nil
+}
+
+func (g *generator) describeFiles() (string, error) {
+	budget := config.GetDiffBudget(g.model)
+	files := g.files
+	full := diff.Join(files)
+
+	strategy := diff.Strategy(config.GetDiffStrategy())
+	if strategy == diff.StrategyAuto {
+		strategy = diff.StrategyFull
+		if diff.EstimateTokens(full) > budget {
+			strategy = diff.StrategySummarize
+			if len(diff.Chunks(files, budget)) > config.GetDiffMaxChunks() {
+				strategy = diff.StrategyStat
+			}
+		}
+	}
+
+	switch strategy {
+	case diff.StrategyFull:
+		return full, nil
+	case diff.StrategySummarize:
+		summary, err := g.summarize(files, budget)
+		if err == nil && diff.EstimateTokens(summary) <= budget {
+			return summary, nil
+		}
+		// An interrupted run stops here rather than carrying on without
+		// the summaries
+		if g.ctx.Err() != nil {
+			return "", g.ctx.Err()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not summarize the changes (%v); using the list of changed files instead.\n", err)
+		}
+		return diff.OutlineNote + "\n\n" + diff.Outline(files), nil
+	case diff.StrategyStat:
+		return diff.OutlineNote + "\n\n" + diff.Outline(files), nil
+	}
+	return "", fmt.Errorf("unknown diff_strategy %q, expected one of %v", strategy, diff.Strategies)
+}
+
+// summarize runs the map step: every chunk of the diff is summarised on its
+// own, and the summaries replace the diff in the final prompt
+func (g *generator) summarize(files []diff.File, budget int) (string, error) {
+	chunks := diff.Chunks(files, budget)
+	if g.llm == nil {
+		return placeholderSummaries(files, chunks), nil
+	}
+
+	spinner := spinner.New()
+	spinner.StartMessage(fmt.Sprintf("Summarizing %d change(s) with %s model...", len(chunks), g.model))
+	defer spinner.Stop()
+
+	summaries := map[string][]string{}
+	for i, chunk := range chunks {
+		spinner.SetMessage(fmt.Sprintf("Summarizing change %d/%d (%s) with %s model...", i+1, len(chunks), chunk.Path, g.model))
+		resp, err := g.send(g.newRequest(prompt.Summary(chunk.Path, chunk.Patch)), nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize changes to %s: %w", chunk.Path, err)
+		}
+		g.record(resp)
+		summaries[chunk.Path] = append(summaries[chunk.Path], strings.TrimSpace(resp.Text))
+	}
+
+	var b strings.Builder
+	b.WriteString(diff.SummaryNote + "\n\n")
+	for _, f := range files {
+		line := f.StatLine()
+		if s := summaries[f.Path]; len(s) > 0 {
+			line += ": " + strings.Join(s, " ")
+		}
+		b.WriteString("- " + line + "\n")
+	}
+	return b.String(), nil
+}
+
+// placeholderSummaries stands in for the summaries when the prompt is
+// rendered without a model server, showing where each one would go
+func placeholderSummaries(files []diff.File, chunks []diff.Chunk) string {
+	count := map[string]int{}
+	for _, c := range chunks {
+		count[c.Path]++
+	}
+
+	var b strings.Builder
+	b.WriteString(diff.SummaryNote + "\n\n")
+	for _, f := range files {
+		line := f.StatLine()
+		if n := count[f.Path]; n > 0 {
+			line += fmt.Sprintf(": <%d summary(ies) written by the model>", n)
+		}
+		b.WriteString("- " + line + "\n")
+	}
+	return b.String()
+}
+
+// ErrSecretsDetected is returned when block_secrets is on and the staged
+// changes contain credentials
+var ErrSecretsDetected = errors.New("secrets detected in the staged changes")
+
+// blockSecrets refuses to continue when block_secrets is on and the staged
+// changes contain credentials. Every staged file is scanned, including the
+// ones left out by the ignore rules, whether or not redaction is on.
+func (g *generator) blockSecrets() error {
+	if !config.GetBlockSecrets() {
+		return nil
+	}
+	r, err := redact.New(config.GetRedactPatterns())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range append(append([]diff.File{}, g.files...), g.omitted...) {
+		r.Redact(f.Path, f.Header)
+		for _, h := range f.Hunks {
+			r.Redact(f.Path, h.Header)
+			r.Redact(f.Path, h.Body)
+		}
+	}
+	if r.Secrets() {
+		return fmt.Errorf("%w, refusing to continue:\n%s", ErrSecretsDetected, redact.Report(r.Findings()))
+	}
+	return nil
+}
+
+// redact masks secrets and personal data in the diffs shown to the model,
+// reporting what was masked when asked to
+func (g *generator) redact() error {
+	r, err := redact.New(config.GetRedactPatterns())
+	if err != nil {
+		return err
+	}
+
+	for i, f := range g.files {
+		f.Header = r.Redact(f.Path, f.Header)
+		hunks := make([]diff.Hunk, len(f.Hunks))
+		for j, h := range f.Hunks {
+			hunks[j] = diff.Hunk{Header: r.Redact(f.Path, h.Header), Body: r.Redact(f.Path, h.Body)}
+		}
+		f.Hunks = hunks
+		g.files[i] = f
+	}
+
+	findings := r.Findings()
+	if config.GetShowRedactions() {
+		if len(findings) == 0 {
+			fmt.Fprintln(os.Stderr, "Nothing was redacted from the staged changes.")
+		} else {
+			fmt.Fprintf(os.Stderr, "Redacted %d value(s) before sending the changes to the model:\n%s", len(findings), redact.Report(findings))
+		}
+	}
+	return nil
+}
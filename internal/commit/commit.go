@@ -1,59 +1,149 @@
 package commit
 
 import (
+	"context"
 	"fmt"
+	"os"
 
 	"github.com/dakoctba/cmt/internal/config"
 	"github.com/dakoctba/cmt/internal/git"
-	"github.com/dakoctba/cmt/internal/ollama"
-	"github.com/dakoctba/cmt/internal/spinner"
+	"github.com/dakoctba/cmt/internal/message"
+	"github.com/dakoctba/cmt/internal/ui"
+	"github.com/dakoctba/cmt/internal/validator"
 	"github.com/spf13/cobra"
 )
 
-// RunCommit is the main function for generating commit messages
+// RunCommit is the main function for generating commit messages. It stops
+// when the command's context is cancelled, e.g. on Ctrl-C.
 func RunCommit(cmd *cobra.Command, args []string) error {
-	// Check if ollama is installed
-	if err := ollama.CheckInstallation(); err != nil {
-		return err
+	ctx := context.Background()
+	if cmd != nil && cmd.Context() != nil {
+		ctx = cmd.Context()
 	}
 
-	// Check if we're in a git repository
-	if err := git.CheckRepo(); err != nil {
+	format := config.GetOutput()
+	if err := checkOutput(format); err != nil {
 		return err
 	}
 
-	// Get staged changes
-	diff, err := git.GetStagedDiff()
+	g, err := newGenerator(ctx)
 	if err != nil {
 		return err
 	}
 
-	if diff == "" {
-		return fmt.Errorf("no staged changes found. Please stage your changes using 'git add' first")
+	interactive := format == "" && !config.GetAutoCommit() && ui.IsTerminal(os.Stdin)
+	prompter := ui.NewPrompter(ctx, os.Stdin, os.Stdout)
+
+	// Offer to download a missing model before asking it anything. The
+	// question is kept off the output of scripts.
+	if err := ensureModel(ctx, g.llm, g.model, prompter, format == "" && ui.IsTerminal(os.Stdin)); err != nil {
+		return err
 	}
 
-	// Get model from config
-	model := config.GetModel()
-	if model == "" {
-		model = "llama3.1"
+	for {
+		var c Candidate
+		if n := config.GetCandidates(); n > 1 {
+			candidates, err := g.candidates(n)
+			if err != nil {
+				return err
+			}
+			if !interactive && !config.GetAutoCommit() && (format == "" || format == OutputJSON) {
+				return printCandidates(os.Stdout, candidates)
+			}
+
+			// Without a terminal to pick on, use the best one
+			c = candidates[0]
+			if interactive {
+				picked, regenerate, err := pickCandidate(prompter, candidates)
+				if err != nil || picked == nil && !regenerate {
+					return err
+				}
+				if regenerate {
+					continue
+				}
+				c = *picked
+			}
+		} else if c, err = g.generate(); err != nil {
+			return err
+		}
+		printViolations(c.Result)
+
+		// Print the message for scripts, committing it first when asked to
+		if format != "" || !interactive && !config.GetAutoCommit() {
+			if config.GetAutoCommit() {
+				if err := git.Commit(ctx, c.Message.String(), commitOptions()); err != nil {
+					return err
+				}
+			}
+			if format == "" {
+				format = OutputShell
+			}
+			return g.print(os.Stdout, c, format)
+		}
+
+		regenerate, err := review(ctx, prompter, c.Message, interactive)
+		if err != nil || !regenerate {
+			return err
+		}
 	}
+}
 
-	// Show loading message with spinner
-	spinner := spinner.New()
-	spinner.Start(model)
+// review shows the message and lets the user accept, edit, regenerate or
+// abort it. It reports whether a new message should be generated.
+func review(ctx context.Context, prompter *ui.Prompter, msg *message.Message, interactive bool) (bool, error) {
+	for {
+		fmt.Println("\nGenerated commit message:")
+		fmt.Println(msg.String())
+		fmt.Println()
 
-	// Generate commit message
-	commitMessage, err := ollama.GenerateCommitMessage(diff, model)
+		if !interactive {
+			return false, git.Commit(ctx, msg.String(), commitOptions())
+		}
 
-	// Stop spinner
-	spinner.Stop()
+		choice, err := prompter.Choose("Commit with this message?", []ui.Choice{
+			{Key: "a", Label: "accept"},
+			{Key: "e", Label: "edit"},
+			{Key: "r", Label: "regenerate"},
+			{Key: "q", Label: "abort"},
+		})
+		if err != nil {
+			return false, err
+		}
 
-	if err != nil {
-		return err
+		switch choice {
+		case "a":
+			return false, git.Commit(ctx, msg.String(), commitOptions())
+		case "e":
+			edited, err := git.EditMessage(ctx, msg.String())
+			if err != nil {
+				return false, err
+			}
+			if msg, err = message.ParseText(edited); err != nil {
+				fmt.Println("Aborting commit due to empty commit message.")
+				return false, nil
+			}
+		case "r":
+			return true, nil
+		default:
+			fmt.Println("Commit aborted.")
+			return false, nil
+		}
 	}
+}
 
-	fmt.Println("\nGenerated commit message:")
-	fmt.Println(commitMessage)
+// commitOptions collects the git commit flags from the configuration
+func commitOptions() git.CommitOptions {
+	return git.CommitOptions{
+		Signoff:  config.GetSignoff(),
+		Sign:     config.GetGPGSign(),
+		SignKey:  config.GetGPGKey(),
+		NoVerify: config.GetNoVerify(),
+	}
+}
 
-	return nil
+// printViolations reports the rules the final message still breaks
+func printViolations(result validator.Result) {
+	for _, v := range result.Violations {
+		fmt.Fprintf(os.Stderr, "%s %s\n", v.Level.Symbol(), v)
+	}
 }
@@ -0,0 +1,394 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dakoctba/cmt/internal/branch"
+	"github.com/dakoctba/cmt/internal/config"
+	"github.com/dakoctba/cmt/internal/diff"
+	"github.com/dakoctba/cmt/internal/git"
+	"github.com/dakoctba/cmt/internal/ignore"
+	"github.com/dakoctba/cmt/internal/message"
+	"github.com/dakoctba/cmt/internal/prompt"
+	"github.com/dakoctba/cmt/internal/provider"
+	"github.com/dakoctba/cmt/internal/scope"
+	"github.com/dakoctba/cmt/internal/spinner"
+	"github.com/dakoctba/cmt/internal/style"
+	"github.com/dakoctba/cmt/internal/validator"
+)
+
+// ErrNoStagedChanges is returned when there is nothing to describe
+var ErrNoStagedChanges = errors.New("no staged changes found. Please stage your changes using 'git add' first")
+
+// generator holds everything needed to ask the model for a commit message
+type generator struct {
+	ctx   context.Context
+	llm   provider.Provider
+	diff  string
+	model string
+	rules validator.Rules
+
+	// files are the per-file diffs shown to the model; omitted are the
+	// files excluded by the ignore rules, which are only mentioned by name
+	files   []diff.File
+	omitted []diff.File
+
+	// paths are the paths of every staged file, in diff order
+	paths []string
+
+	// scopes are the scopes of the staged files, see resolveScopes
+	scopes scope.Result
+
+	// branch is what the branch name says about the change
+	branch branch.Info
+
+	// commitPrompt is the rendered prompt template, see renderPrompt
+	commitPrompt string
+
+	// rounds is the number of times candidates were generated
+	rounds int
+
+	// chain is the model followed by its fallbacks, and active the index of
+	// the first one requests go to, see send
+	chain  []string
+	active int
+
+	// start is when the run began; usage adds up the model requests made
+	// since
+	start time.Time
+	mu    sync.Mutex
+	usage usage
+}
+
+// usage is what the model requests of a run took
+type usage struct {
+	// Model is the model that answered last, as reported by the server
+	Model string
+
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+
+	// Duration is the time spent generating, as reported by the server
+	Duration time.Duration
+}
+
+// recentCommits is the number of commit subjects given to the prompt
+// template
+const recentCommits = 10
+
+// newGenerator checks the environment and collects the staged changes. ctx
+// bounds every git command and model request of the run.
+func newGenerator(ctx context.Context) (*generator, error) {
+	start := time.Now()
+	llm, err := provider.New(config.GetProvider(), config.GetBaseURL(), config.GetAPIKey())
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if the model server is reachable
+	healthCtx, cancel := withTimeout(ctx)
+	err = llm.Health(healthCtx)
+	cancel()
+	if err != nil {
+		return nil, timeoutError(err)
+	}
+
+	g, err := collectChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+	g.llm, g.start = llm, start
+	return g, nil
+}
+
+// collectChanges collects the staged changes and everything the prompt is
+// rendered with, without a model server: a generator without one renders
+// the prompt as a dry run, see summarize
+func collectChanges(ctx context.Context) (*generator, error) {
+	// Check if we're in a git repository
+	if err := git.CheckRepo(ctx); err != nil {
+		return nil, err
+	}
+
+	// Get staged changes
+	files, err := git.GetStagedFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, ErrNoStagedChanges
+	}
+
+	// Get model from config
+	model := config.GetModel()
+	if model == "" {
+		model = "llama3.1"
+	}
+
+	// Load the commitlint rules the message has to satisfy
+	root, err := git.RepoRoot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules, _, err := validator.LoadRules(root)
+	if err != nil {
+		return nil, err
+	}
+
+	// Leave lockfiles, vendored and generated code and binaries out of the
+	// prompt
+	matcher, err := ignore.Load(root, config.GetIgnore(), config.GetIgnoreDefaults())
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{ctx: ctx, diff: diff.Join(files), model: model, rules: rules, start: time.Now()}
+	g.chain = modelChain(model, config.GetFallbackModels())
+	for _, f := range files {
+		g.paths = append(g.paths, f.Path)
+		if f.Binary || matcher.Match(f.Path) {
+			g.omitted = append(g.omitted, f)
+		} else {
+			g.files = append(g.files, f)
+		}
+	}
+
+	// Read the ticket ID and the kind of change from the branch name
+	if err := g.readBranch(); err != nil {
+		return nil, err
+	}
+
+	// Work out the scope from the changed paths
+	if err := g.resolveScopes(root); err != nil {
+		return nil, err
+	}
+
+	// Mask secrets and personal data before anything reaches the model
+	if err := g.blockSecrets(); err != nil {
+		return nil, err
+	}
+	if config.GetRedact() {
+		if err := g.redact(); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// generate asks the model for a commit message and parses its output.
+// Messages breaking the rules are sent back to the model together with the
+// violations until they pass or the configured retries run out.
+func (g *generator) generate() (Candidate, error) {
+	request, err := g.request()
+	if err != nil {
+		return Candidate{}, err
+	}
+	raw, err := g.complete(request)
+
+	for attempt := 0; ; attempt++ {
+		if err != nil {
+			return Candidate{}, err
+		}
+
+		var c Candidate
+		if c, err = g.check(raw); err != nil {
+			return Candidate{}, err
+		}
+		if c.Result.Valid() || attempt >= config.GetLintRetries() {
+			return c, nil
+		}
+
+		fmt.Fprintf(os.Stderr, "\nThe generated message breaks %d commit rule(s); asking the model to fix it...\n", len(c.Result.Errors()))
+		request.Messages = append(request.Messages,
+			provider.Message{Role: "assistant", Content: raw},
+			provider.Message{Role: "user", Content: prompt.Revision(c.Result.Feedback())},
+		)
+		raw, err = g.complete(request)
+	}
+}
+
+// request returns the request asking for a commit message, rendering the
+// prompt on first use
+func (g *generator) request() (provider.Request, error) {
+	if g.commitPrompt == "" {
+		text, err := g.renderPrompt()
+		if err != nil {
+			return provider.Request{}, err
+		}
+		g.commitPrompt = text
+	}
+	return g.newRequest(g.commitPrompt), nil
+}
+
+// newRequest returns a single prompt request with the system prompt and
+// generation options configured for the model
+func (g *generator) newRequest(text string) provider.Request {
+	gen := config.GetGeneration(g.model)
+	req := provider.Prompt(g.model, text)
+	if gen.System != "" {
+		req.Messages = append([]provider.Message{{Role: "system", Content: gen.System}}, req.Messages...)
+	}
+	req.Options = provider.Options{
+		Temperature: gen.Temperature,
+		TopP:        gen.TopP,
+		TopK:        gen.TopK,
+		Seed:        gen.Seed,
+		NumCtx:      gen.NumCtx,
+		NumPredict:  gen.NumPredict,
+		Stop:        gen.Stop,
+		KeepAlive:   gen.KeepAlive,
+	}
+	return req
+}
+
+// check parses the raw model output, adds the branch's ticket and validates
+// the message against the commit rules
+func (g *generator) check(raw string) (Candidate, error) {
+	msg, err := message.Parse(raw)
+	if err != nil {
+		return Candidate{}, fmt.Errorf("failed to read the generated commit message: %w", err)
+	}
+	if err := g.addTicket(msg); err != nil {
+		return Candidate{}, err
+	}
+	return Candidate{Message: msg, Result: g.rules.Validate(msg.String()), Raw: raw}, nil
+}
+
+// withTimeout bounds a model request by the timeout setting
+func withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout := config.GetTimeout(); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// timeoutError explains a model request running out of time
+func timeoutError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("the model did not answer within %s (see the timeout setting): %w", config.GetTimeout(), err)
+	}
+	return err
+}
+
+// record adds a model response to the usage of the run
+func (g *generator) record(resp *provider.Response) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.usage.Model = resp.Model
+	g.usage.Requests++
+	g.usage.PromptTokens += resp.PromptTokens
+	g.usage.CompletionTokens += resp.CompletionTokens
+	g.usage.Duration += resp.Duration
+}
+
+// renderPrompt renders the configured prompt template with the staged
+// changes and the repository's context
+func (g *generator) renderPrompt() (string, error) {
+	text, err := config.GetPromptTemplate()
+	if err != nil {
+		return "", err
+	}
+	// Check the template before summarising a large diff for it
+	if _, err := prompt.Parse(text); err != nil {
+		return "", err
+	}
+
+	changes, err := g.describeChanges()
+	if err != nil {
+		return "", err
+	}
+	data := prompt.Data{
+		Diff:     changes,
+		Files:    g.paths,
+		Types:    g.rules.Types(),
+		Scopes:   g.scopes.Scopes,
+		Scope:    g.scopes.Scope(config.GetScopeMultiple()),
+		Branch:   g.branch.Name,
+		Ticket:   g.branch.Ticket,
+		Language: config.GetLanguage(),
+	}
+
+	// Only hint at a type the rules allow
+	for _, t := range data.Types {
+		if t == g.branch.CommitType {
+			data.TypeHint = t
+		}
+	}
+	if data.Style, err = style.Learn(g.ctx, config.GetStyleCommits(), config.GetStyleExamples()); err != nil {
+		return "", err
+	}
+	if git.HasCommits(g.ctx) {
+		commits, err := git.Log(g.ctx, "", recentCommits)
+		if err != nil {
+			return "", err
+		}
+		for _, c := range commits {
+			subject, _, _ := strings.Cut(c.Message, "\n")
+			data.RecentCommits = append(data.RecentCommits, subject)
+		}
+	}
+	return prompt.Render(text, data)
+}
+
+// complete runs a model request, showing a spinner until the first token
+// arrives when streaming is enabled, and returns the raw model output
+func (g *generator) complete(req provider.Request) (string, error) {
+	// Show loading message with spinner
+	spinner := spinner.New()
+	spinner.Start(req.Model)
+	defer spinner.Stop()
+
+	var fn provider.TokenFunc
+	if config.GetStream() {
+		// Replace the spinner with the model output as soon as it starts talking
+		out := &tokenPrinter{onFirst: spinner.Stop}
+		defer out.Finish()
+		fn = out.Print
+	}
+
+	resp, err := g.send(req, fn)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate commit message: %w", err)
+	}
+	g.record(resp)
+	return strings.TrimSpace(resp.Text), nil
+}
+
+// tokenPrinter writes streamed tokens to stderr, skipping leading
+// whitespace and running onFirst right before the first visible token. Like
+// the spinner it is only a preview, so it stays out of stdout.
+type tokenPrinter struct {
+	onFirst func()
+	started bool
+}
+
+// Print writes a single token
+func (p *tokenPrinter) Print(token string) error {
+	if !p.started {
+		token = strings.TrimLeft(token, " \t\r\n")
+		if token == "" {
+			return nil
+		}
+		p.started = true
+		if p.onFirst != nil {
+			p.onFirst()
+		}
+	}
+	fmt.Fprint(os.Stderr, token)
+	return nil
+}
+
+// Finish terminates the streamed output line, if anything was printed
+func (p *tokenPrinter) Finish() {
+	if p.started {
+		fmt.Fprintln(os.Stderr)
+	}
+}
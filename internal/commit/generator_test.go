@@ -0,0 +1,171 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/dakoctba/cmt/internal/provider"
+	"github.com/dakoctba/cmt/internal/validator"
+	"github.com/spf13/viper"
+)
+
+// answerProvider answers its requests in turn, failing a request whose
+// error is set
+type answerProvider struct {
+	provider.Provider
+	answers []string
+	errs    []error
+	calls   int
+}
+
+func (p *answerProvider) Generate(ctx context.Context, req provider.Request) (*provider.Response, error) {
+	i := p.calls
+	p.calls++
+	if i < len(p.errs) && p.errs[i] != nil {
+		return nil, p.errs[i]
+	}
+	return &provider.Response{Model: req.Model, Text: p.answers[i]}, nil
+}
+
+func (p *answerProvider) Stream(ctx context.Context, req provider.Request, fn provider.TokenFunc) (*provider.Response, error) {
+	return p.Generate(ctx, req)
+}
+
+func TestGenerate(t *testing.T) {
+	errBad := errors.New("bad request")
+	tests := []struct {
+		name      string
+		answers   []string
+		errs      []error
+		want      string
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			name:      "should return a valid message at once",
+			answers:   []string{"feat: add the feature"},
+			want:      "feat: add the feature",
+			wantCalls: 1,
+		},
+		{
+			name:      "should ask the model to fix a message breaking the rules",
+			answers:   []string{"Added the feature.", "feat: add the feature"},
+			want:      "feat: add the feature",
+			wantCalls: 2,
+		},
+		{
+			name:      "should report a failed request for a fixed message",
+			answers:   []string{"Added the feature.", ""},
+			errs:      []error{nil, errBad},
+			wantErr:   errBad,
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("lint_retries", 1)
+			viper.Set("stream", false)
+			defer viper.Reset()
+
+			llm := &answerProvider{answers: tt.answers, errs: tt.errs}
+			g := &generator{
+				ctx:          context.Background(),
+				llm:          llm,
+				model:        "llama3.1",
+				rules:        validator.DefaultRules(),
+				commitPrompt: "Describe the change.",
+				chain:        []string{"llama3.1"},
+			}
+
+			c, err := g.generate()
+			if llm.calls != tt.wantCalls {
+				t.Errorf("requests = %d, want %d", llm.calls, tt.wantCalls)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("generate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("generate() error = %v", err)
+			}
+			if got := c.Message.String(); got != tt.want {
+				t.Errorf("generate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		settings    map[string]any
+		wantRoles   []string
+		wantSystem  string
+		wantOptions string
+	}{
+		{
+			name:        "should send the prompt alone by default",
+			wantRoles:   []string{"user"},
+			wantOptions: "temperature=<nil> seed=<nil> num_ctx=8192 stop=[]",
+		},
+		{
+			name: "should apply the model's overrides and system prompt",
+			settings: map[string]any{
+				"temperature": 0.7,
+				"seed":        42,
+				"stop":        []string{"\n\n\n"},
+				"system":      "You write commit messages.",
+				"models":      map[string]any{"llama3.1": map[string]any{"temperature": 0, "system": "Be terse."}},
+			},
+			wantRoles:   []string{"system", "user"},
+			wantSystem:  "Be terse.",
+			wantOptions: "temperature=0 seed=42 num_ctx=8192 stop=[\n\n\n]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			for key, value := range tt.settings {
+				viper.Set(key, value)
+			}
+			defer viper.Reset()
+
+			req := (&generator{model: "llama3.1"}).newRequest("Describe the change.")
+
+			var roles []string
+			for _, m := range req.Messages {
+				roles = append(roles, m.Role)
+			}
+			if fmt.Sprint(roles) != fmt.Sprint(tt.wantRoles) {
+				t.Fatalf("message roles = %v, want %v", roles, tt.wantRoles)
+			}
+			if tt.wantSystem != "" && req.Messages[0].Content != tt.wantSystem {
+				t.Errorf("system prompt = %q, want %q", req.Messages[0].Content, tt.wantSystem)
+			}
+			if last := req.Messages[len(req.Messages)-1]; last.Content != "Describe the change." {
+				t.Errorf("prompt = %q, want the rendered prompt", last.Content)
+			}
+
+			o := req.Options
+			got := fmt.Sprintf("temperature=%v seed=%v num_ctx=%d stop=%v", deref(o.Temperature), deref(o.Seed), o.NumCtx, o.Stop)
+			if got != tt.wantOptions {
+				t.Errorf("options = %q, want %q", got, tt.wantOptions)
+			}
+		})
+	}
+}
+
+// deref returns what p points to, or nil
+func deref[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
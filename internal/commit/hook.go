@@ -0,0 +1,67 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dakoctba/cmt/internal/hook"
+)
+
+// RunHook fills the message file git passes to prepare-commit-msg with a
+// generated message and leaves the staged diff commented out below it.
+// Commits that already have a message (-m, merges, amends) are left alone.
+func RunHook(ctx context.Context, msgFile, source string) error {
+	if !hook.ShouldRun(source) {
+		return nil
+	}
+
+	g, err := newGenerator(ctx)
+	if errors.Is(err, ErrNoStagedChanges) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	c, err := g.generate()
+	if err != nil {
+		return err
+	}
+
+	existing, err := os.ReadFile(msgFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read commit message file: %v", err)
+	}
+
+	var b strings.Builder
+	b.WriteString(c.Message.String() + "\n\n")
+	for _, v := range c.Result.Violations {
+		b.WriteString(fmt.Sprintf("# %s %s\n", v.Level.Symbol(), v))
+	}
+	b.WriteString(commentTemplate(string(existing)))
+	b.WriteString("#\n# Staged changes:\n#\n")
+	b.WriteString(hook.CommentOut(g.diff))
+
+	if err := os.WriteFile(msgFile, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write commit message file: %v", err)
+	}
+	return nil
+}
+
+// commentTemplate keeps what git already put in the message file (status
+// comments, commit template) without letting it leak into the message
+func commentTemplate(text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "#"):
+			b.WriteString(line + "\n")
+		case strings.TrimSpace(line) != "":
+			b.WriteString("# " + line + "\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dakoctba/cmt/internal/catalog"
+	"github.com/dakoctba/cmt/internal/config"
+	"github.com/dakoctba/cmt/internal/progress"
+	"github.com/dakoctba/cmt/internal/provider"
+	"github.com/dakoctba/cmt/internal/ui"
+)
+
+// PullModel downloads a model to the server, drawing a progress bar on
+// stderr. Only providers managing their models, such as Ollama, can pull.
+func PullModel(ctx context.Context, llm provider.Provider, name string) error {
+	manager, ok := llm.(provider.Manager)
+	if !ok {
+		return fmt.Errorf("the %s provider cannot pull models; download %s with the server's own tools", llm.Name(), name)
+	}
+
+	bar := progress.New("Pulling " + name)
+	err := manager.Pull(ctx, name, func(p provider.Progress) error {
+		bar.Update(p.Status, p.Completed, p.Total)
+		return nil
+	})
+	bar.Finish()
+	if err != nil {
+		return fmt.Errorf("failed to pull %s: %w", name, err)
+	}
+	return nil
+}
+
+// ensureModel makes sure the server has the model before the first request
+// rather than letting the request fail, or download it behind the spinner.
+// A missing model is pulled when auto_pull is true or, when it is unset,
+// if the user agrees; prompter is only asked when ask is true. Without the
+// model the run stops, unless fallback models may answer instead. Servers
+// that cannot pull models are left to the first request.
+func ensureModel(ctx context.Context, llm provider.Provider, model string, prompter *ui.Prompter, ask bool) error {
+	manager, ok := llm.(provider.Manager)
+	if !ok {
+		return nil
+	}
+	showCtx, cancel := withTimeout(ctx)
+	_, err := manager.ShowModel(showCtx, model)
+	cancel()
+	if !errors.Is(err, provider.ErrModelNotFound) {
+		return nil
+	}
+
+	var pull bool
+	switch auto := config.GetAutoPull(); {
+	case auto != nil:
+		pull = *auto
+	case ask:
+		question := fmt.Sprintf("%s is not on the server. Download it now?", model)
+		if e, ok := catalog.Lookup(model); ok {
+			question = fmt.Sprintf("%s is not on the server. Download it now (about %s)?", model, e.Size)
+		}
+		if pull, err = prompter.Confirm(question, true); err != nil {
+			return err
+		}
+	}
+	if pull {
+		return PullModel(ctx, llm, model)
+	}
+
+	if len(config.GetFallbackModels()) > 0 {
+		return nil
+	}
+	return fmt.Errorf(`%w: %s is not on the server; download it with "cmt models pull %s", or set auto_pull: true`, provider.ErrModelNotFound, model, model)
+}
@@ -0,0 +1,127 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/dakoctba/cmt/internal/provider"
+	"github.com/dakoctba/cmt/internal/ui"
+	"github.com/spf13/viper"
+)
+
+// modelServer is a provider managing its models, whose registry offers
+// every model unless registry lists the ones it has
+type modelServer struct {
+	provider.Provider
+	models   []string
+	registry []string
+
+	// pulls are the models asked to be pulled, in order
+	pulls []string
+}
+
+func (s *modelServer) Name() string { return provider.Ollama }
+
+func (s *modelServer) ShowModel(ctx context.Context, name string) (provider.Model, error) {
+	if indexOf(s.models, name) < 0 {
+		return provider.Model{}, fmt.Errorf("%s: %w", name, provider.ErrModelNotFound)
+	}
+	return provider.Model{Name: name}, nil
+}
+
+func (s *modelServer) Pull(ctx context.Context, name string, fn provider.ProgressFunc) error {
+	s.pulls = append(s.pulls, name)
+	if s.registry != nil && indexOf(s.registry, name) < 0 {
+		return errors.New("file does not exist")
+	}
+	s.models = append(s.models, name)
+	return fn(provider.Progress{Status: "success"})
+}
+
+func TestEnsureModel(t *testing.T) {
+	tests := []struct {
+		name      string
+		settings  map[string]any
+		models    []string
+		registry  []string
+		ask       bool
+		answer    string
+		wantPulls []string
+		wantErr   bool
+		wantIs    error
+	}{
+		{
+			name:   "should leave a model on the server alone",
+			models: []string{"llama3.1"},
+		},
+		{
+			name:      "should pull the model when auto_pull is true",
+			settings:  map[string]any{"auto_pull": true},
+			wantPulls: []string{"llama3.1"},
+		},
+		{
+			name:     "should not pull the model when auto_pull is false",
+			settings: map[string]any{"auto_pull": false},
+			ask:      true,
+			wantErr:  true,
+			wantIs:   provider.ErrModelNotFound,
+		},
+		{
+			name:      "should pull the model when the user agrees",
+			ask:       true,
+			answer:    "y\n",
+			wantPulls: []string{"llama3.1"},
+		},
+		{
+			name:    "should not pull the model when the user declines",
+			ask:     true,
+			answer:  "n\n",
+			wantErr: true,
+			wantIs:  provider.ErrModelNotFound,
+		},
+		{
+			name:    "should not ask scripts whether to pull",
+			wantErr: true,
+			wantIs:  provider.ErrModelNotFound,
+		},
+		{
+			name:     "should leave a missing model to the fallback models",
+			settings: map[string]any{"fallback_models": []string{"phi3"}},
+		},
+		{
+			name:      "should report models the registry does not have",
+			settings:  map[string]any{"auto_pull": true},
+			registry:  []string{"phi3"},
+			wantPulls: []string{"llama3.1"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			for key, value := range tt.settings {
+				viper.Set(key, value)
+			}
+			defer viper.Reset()
+
+			server := &modelServer{models: tt.models, registry: tt.registry}
+			prompter := ui.NewPrompter(context.Background(), strings.NewReader(tt.answer), io.Discard)
+
+			err := ensureModel(context.Background(), server, "llama3.1", prompter, tt.ask)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ensureModel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Errorf("ensureModel() error = %v, want %v", err, tt.wantIs)
+			}
+			if fmt.Sprint(server.pulls) != fmt.Sprint(tt.wantPulls) {
+				t.Errorf("pulls = %v, want %v", server.pulls, tt.wantPulls)
+			}
+		})
+	}
+}
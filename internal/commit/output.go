@@ -0,0 +1,118 @@
+package commit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dakoctba/cmt/internal/message"
+	"github.com/dakoctba/cmt/internal/validator"
+)
+
+// Output formats of the generated message, see the output setting
+const (
+	// OutputText is the plain message, ready for git commit -F -
+	OutputText = "text"
+
+	// OutputJSON is the message fields together with the model, timings and
+	// token counts
+	OutputJSON = "json"
+
+	// OutputShell is a safely quoted git commit command
+	OutputShell = "shell"
+
+	// OutputRaw is the model output as is, for debugging
+	OutputRaw = "raw"
+)
+
+// Outputs lists the output formats
+var Outputs = []string{OutputText, OutputJSON, OutputShell, OutputRaw}
+
+// checkOutput reports an unknown output format. An empty format, the
+// default, reviews the message in a terminal and prints a git commit
+// command otherwise.
+func checkOutput(format string) error {
+	if format == "" {
+		return nil
+	}
+	for _, f := range Outputs {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q, expected one of %v", format, Outputs)
+}
+
+// report is the JSON output of a generated message
+type report struct {
+	*message.Message
+	Header     string                `json:"header"`
+	Text       string                `json:"message"`
+	Violations []validator.Violation `json:"violations"`
+
+	Provider string        `json:"provider"`
+	Model    string        `json:"model"`
+	Timings  reportTimings `json:"timings"`
+	Tokens   reportTokens  `json:"tokens"`
+}
+
+type reportTimings struct {
+	// TotalMS is the time the whole run took, GenerationMS the time the
+	// server spent generating
+	TotalMS      int64 `json:"total_ms"`
+	GenerationMS int64 `json:"generation_ms"`
+}
+
+type reportTokens struct {
+	Requests   int `json:"requests"`
+	Prompt     int `json:"prompt"`
+	Completion int `json:"completion"`
+}
+
+// print writes the message to w in the given format
+func (g *generator) print(w io.Writer, c Candidate, format string) error {
+	switch format {
+	case OutputText:
+		_, err := fmt.Fprintln(w, c.Message.String())
+		return err
+	case OutputShell:
+		text, err := c.Message.Render(message.FormatShell)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, text)
+		return err
+	case OutputRaw:
+		_, err := fmt.Fprintln(w, c.Raw)
+		return err
+	case OutputJSON:
+		g.mu.Lock()
+		usage := g.usage
+		g.mu.Unlock()
+		if usage.Model == "" {
+			usage.Model = g.model
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report{
+			Message:    c.Message,
+			Header:     c.Message.Header(),
+			Text:       c.Message.String(),
+			Violations: c.Result.Violations,
+			Provider:   g.llm.Name(),
+			Model:      usage.Model,
+			Timings: reportTimings{
+				TotalMS:      time.Since(g.start).Milliseconds(),
+				GenerationMS: usage.Duration.Milliseconds(),
+			},
+			Tokens: reportTokens{
+				Requests:   usage.Requests,
+				Prompt:     usage.PromptTokens,
+				Completion: usage.CompletionTokens,
+			},
+		})
+	}
+	return checkOutput(format)
+}
@@ -0,0 +1,103 @@
+package commit
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dakoctba/cmt/internal/message"
+	"github.com/dakoctba/cmt/internal/provider"
+)
+
+func TestPrint(t *testing.T) {
+	const raw = "Here you go:\n\nfeat: add the feature\n\nExplain why it's needed."
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "should print the plain message",
+			format: OutputText,
+			want:   "feat: add the feature\n\nExplain why it's needed.\n",
+		},
+		{
+			name:   "should print a quoted git commit command",
+			format: OutputShell,
+			want:   `git commit -m 'feat: add the feature' -m 'Explain why it'\''s needed.'` + "\n",
+		},
+		{
+			name:   "should print the raw model output",
+			format: OutputRaw,
+			want:   raw + "\n",
+		},
+		{
+			name:    "should reject an unknown format",
+			format:  "yaml",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := message.Parse(raw)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			var out strings.Builder
+			err = (&generator{}).print(&out, Candidate{Message: msg, Raw: raw}, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("print() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if out.String() != tt.want {
+				t.Errorf("print() = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintJSON(t *testing.T) {
+	llm, err := provider.New("ollama", "http://127.0.0.1:1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := message.Parse("feat(api): add the feature")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	g := &generator{llm: llm, model: "llama3.1"}
+	g.record(&provider.Response{Model: "phi3", PromptTokens: 10, CompletionTokens: 4})
+	g.record(&provider.Response{Model: "phi3", PromptTokens: 12, CompletionTokens: 5})
+
+	var out strings.Builder
+	if err := g.print(&out, Candidate{Message: msg}, OutputJSON); err != nil {
+		t.Fatalf("print() error = %v", err)
+	}
+
+	var got struct {
+		Type     string `json:"type"`
+		Scope    string `json:"scope"`
+		Header   string `json:"header"`
+		Provider string `json:"provider"`
+		Model    string `json:"model"`
+		Tokens   struct {
+			Requests   int `json:"requests"`
+			Prompt     int `json:"prompt"`
+			Completion int `json:"completion"`
+		} `json:"tokens"`
+	}
+	if err := json.Unmarshal([]byte(out.String()), &got); err != nil {
+		t.Fatalf("output %q is not JSON: %v", out.String(), err)
+	}
+	if got.Type != "feat" || got.Scope != "api" || got.Header != "feat(api): add the feature" {
+		t.Errorf("message fields = %+v, want the parsed message", got)
+	}
+	if got.Provider != "ollama" || got.Model != "phi3" {
+		t.Errorf("provider, model = %q, %q, want the model that answered", got.Provider, got.Model)
+	}
+	if got.Tokens.Requests != 2 || got.Tokens.Prompt != 22 || got.Tokens.Completion != 9 {
+		t.Errorf("tokens = %+v, want the sum of both requests", got.Tokens)
+	}
+}
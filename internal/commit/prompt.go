@@ -0,0 +1,25 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// ShowPrompt writes the prompt that would be sent to the model for the
+// staged changes, rendered from the configured template. It is a dry run
+// that needs no model server: the summaries of a large diff are shown as
+// placeholders.
+func ShowPrompt(ctx context.Context, w io.Writer) error {
+	g, err := collectChanges(ctx)
+	if err != nil {
+		return err
+	}
+
+	text, err := g.renderPrompt()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(w, text)
+	return err
+}
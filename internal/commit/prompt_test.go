@@ -0,0 +1,52 @@
+package commit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dakoctba/cmt/internal/git/gittest"
+	"github.com/spf13/viper"
+)
+
+func TestShowPrompt(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]any
+		want     string
+	}{
+		{
+			name: "should render the diff without a model server",
+			want: "+package main",
+		},
+		{
+			name:     "should show where the summaries would go",
+			settings: map[string]any{"diff_strategy": "summarize"},
+			want:     "main.go | +1 -0 (added): <1 summary(ies) written by the model>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			dir := gittest.Chdir(t)
+			gittest.Stage(t, dir, "main.go", "package main")
+
+			viper.Reset()
+			viper.Set("base_url", "http://127.0.0.1:1")
+			viper.Set("style_commits", 0)
+			for key, value := range tt.settings {
+				viper.Set(key, value)
+			}
+			defer viper.Reset()
+
+			var out strings.Builder
+			if err := ShowPrompt(context.Background(), &out); err != nil {
+				t.Fatalf("ShowPrompt() error = %v", err)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("ShowPrompt() = %q, want it to contain %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package commit
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dakoctba/cmt/internal/config"
+	"github.com/dakoctba/cmt/internal/provider"
+	"github.com/dakoctba/cmt/internal/ui"
+)
+
+// send runs a model request, streaming tokens to fn when it is not nil.
+// Temporary server errors are retried with exponential backoff; when the
+// model is missing or keeps failing the fallback models are tried in turn,
+// keeping the request's messages and options. Once a fallback answers, the
+// following requests of the run go straight to it.
+func (g *generator) send(req provider.Request, fn provider.TokenFunc) (*provider.Response, error) {
+	g.mu.Lock()
+	chain := g.chain[g.active:]
+	g.mu.Unlock()
+
+	// Notes go below the streamed text rather than over it
+	var streamed bool
+	var stream provider.TokenFunc
+	if fn != nil {
+		stream = func(token string) error {
+			streamed = streamed || strings.TrimSpace(token) != ""
+			return fn(token)
+		}
+	}
+
+	// Once part of an answer has been printed, the requests repeating it
+	// are not streamed, so that the next answer is not printed after the
+	// beginning of the failed one
+	tokens := func() provider.TokenFunc {
+		if streamed {
+			return nil
+		}
+		return stream
+	}
+
+	var err error
+	for i, model := range chain {
+		if i > 0 {
+			notef(streamed, "%s failed: %v; trying %s%s...\n", chain[i-1], err, model, discarded(streamed))
+		}
+		req.Model = model
+
+		var resp *provider.Response
+		if resp, err = g.retry(req, tokens, &streamed); err == nil {
+			if i > 0 {
+				// The streamed text is still to be ended by its printer
+				end := "\n"
+				if streamed {
+					end = ""
+				}
+				notef(streamed, "Answered by %s instead of %s."+end, model, chain[0])
+				g.mu.Lock()
+				g.active = indexOf(g.chain, model)
+				g.mu.Unlock()
+			}
+			return resp, nil
+		}
+		if g.ctx.Err() != nil {
+			return nil, err
+		}
+	}
+	if len(chain) > 1 {
+		return nil, fmt.Errorf("no model answered (tried %s): %w", strings.Join(chain, ", "), err)
+	}
+	return nil, err
+}
+
+// retry runs the request on its model, repeating it after a growing wait
+// while the server fails temporarily. tokens returns the function the
+// answer is streamed to, if any, for each attempt.
+func (g *generator) retry(req provider.Request, tokens func() provider.TokenFunc, streamed *bool) (*provider.Response, error) {
+	backoff := config.GetRetryBackoff()
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := withTimeout(g.ctx)
+		resp, err := provider.Complete(ctx, g.llm, req, tokens())
+		cancel()
+		if err == nil || !provider.Transient(err) || attempt >= config.GetRetries() {
+			return resp, timeoutError(err)
+		}
+
+		notef(*streamed, "%s is not ready (%v); retrying in %s (%d/%d)%s...\n", req.Model, err, backoff, attempt+1, config.GetRetries(), discarded(*streamed))
+		select {
+		case <-g.ctx.Done():
+			return nil, g.ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// modelChain returns the model followed by its fallbacks, without repeats
+func modelChain(model string, fallbacks []string) []string {
+	chain := []string{model}
+	for _, m := range fallbacks {
+		if m != "" && indexOf(chain, m) < 0 {
+			chain = append(chain, m)
+		}
+	}
+	return chain
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// discarded tells the user that the partial answer printed before a failed
+// request is not the one kept
+func discarded(streamed bool) string {
+	if streamed {
+		return ", discarding the partial message above"
+	}
+	return ""
+}
+
+// notef writes a note on stderr, on a line of its own: below the streamed
+// text when the model has started answering, over the spinner otherwise
+func notef(streamed bool, format string, args ...any) {
+	var prefix string
+	switch {
+	case streamed:
+		prefix = "\n"
+	case ui.IsTerminal(os.Stderr):
+		prefix = "\r\033[K"
+	}
+	fmt.Fprintf(os.Stderr, prefix+format, args...)
+}
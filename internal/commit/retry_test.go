@@ -0,0 +1,222 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/dakoctba/cmt/internal/config"
+	"github.com/dakoctba/cmt/internal/provider"
+	"github.com/spf13/viper"
+)
+
+// flakyProvider streams part of an answer and then fails temporarily for
+// its first failures requests, answering the ones after them
+type flakyProvider struct {
+	provider.Provider
+	failures int
+
+	// streamed records, for each request, whether it was streamed
+	streamed []bool
+}
+
+func (p *flakyProvider) Generate(ctx context.Context, req provider.Request) (*provider.Response, error) {
+	return p.Stream(ctx, req, nil)
+}
+
+func (p *flakyProvider) Stream(ctx context.Context, req provider.Request, fn provider.TokenFunc) (*provider.Response, error) {
+	p.streamed = append(p.streamed, fn != nil)
+	if p.failures > 0 {
+		p.failures--
+		if fn != nil {
+			fn("feat: half")
+		}
+		return nil, fmt.Errorf("connection reset: %w", provider.ErrTemporary)
+	}
+	if fn != nil {
+		fn("feat: whole")
+	}
+	return &provider.Response{Model: req.Model, Text: "feat: whole"}, nil
+}
+
+// scriptedProvider fails each request to a model with the next of the
+// model's errors and answers once they run out
+type scriptedProvider struct {
+	provider.Provider
+	errs map[string][]error
+
+	// asked are the models of the requests, in order
+	asked []string
+}
+
+func (p *scriptedProvider) Generate(ctx context.Context, req provider.Request) (*provider.Response, error) {
+	p.asked = append(p.asked, req.Model)
+	if errs := p.errs[req.Model]; len(errs) > 0 {
+		p.errs[req.Model] = errs[1:]
+		return nil, errs[0]
+	}
+	return &provider.Response{Model: req.Model, Text: "feat: whole"}, nil
+}
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		settings     map[string]any
+		wantStreamed []bool
+		wantTokens   string
+	}{
+		{
+			name:         "should stream a request answering at once",
+			wantStreamed: []bool{true},
+			wantTokens:   "feat: whole",
+		},
+		{
+			name:         "should not stream a retry after part of an answer was printed",
+			failures:     1,
+			wantStreamed: []bool{true, false},
+			wantTokens:   "feat: half",
+		},
+		{
+			name:         "should not stream a fallback after part of an answer was printed",
+			failures:     1,
+			settings:     map[string]any{"retries": 0, "fallback_models": []string{"phi3"}},
+			wantStreamed: []bool{true, false},
+			wantTokens:   "feat: half",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("retry_backoff", "1ms")
+			for key, value := range tt.settings {
+				viper.Set(key, value)
+			}
+			defer viper.Reset()
+
+			llm := &flakyProvider{failures: tt.failures}
+			g := &generator{ctx: context.Background(), llm: llm, chain: modelChain("llama3.1", []string{"phi3"})}
+
+			var tokens string
+			resp, err := g.send(provider.Prompt("llama3.1", "x"), func(token string) error {
+				tokens += token
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("send() error = %v", err)
+			}
+			if resp.Text != "feat: whole" {
+				t.Errorf("send() text = %q, want %q", resp.Text, "feat: whole")
+			}
+			if fmt.Sprint(llm.streamed) != fmt.Sprint(tt.wantStreamed) {
+				t.Errorf("requests streamed = %v, want %v", llm.streamed, tt.wantStreamed)
+			}
+			if tokens != tt.wantTokens {
+				t.Errorf("tokens printed = %q, want %q", tokens, tt.wantTokens)
+			}
+		})
+	}
+}
+
+func TestSendRetries(t *testing.T) {
+	var (
+		errTemporary = fmt.Errorf("server busy: %w", provider.ErrTemporary)
+		errMissing   = fmt.Errorf("llama3.1: %w", provider.ErrModelNotFound)
+		errBad       = errors.New("bad request")
+	)
+	tests := []struct {
+		name      string
+		settings  map[string]any
+		errs      map[string][]error
+		wantAsked []string
+		wantModel string
+		wantNext  string
+		wantErr   error
+	}{
+		{
+			name:      "should retry temporary server errors",
+			errs:      map[string][]error{"llama3.1": {errTemporary, errTemporary}},
+			wantAsked: []string{"llama3.1", "llama3.1", "llama3.1"},
+			wantModel: "llama3.1",
+			wantNext:  "llama3.1",
+		},
+		{
+			name:      "should give up after the configured retries",
+			settings:  map[string]any{"retries": 1},
+			errs:      map[string][]error{"llama3.1": {errTemporary, errTemporary}},
+			wantAsked: []string{"llama3.1", "llama3.1"},
+			wantErr:   provider.ErrTemporary,
+		},
+		{
+			name:      "should not retry other errors",
+			errs:      map[string][]error{"llama3.1": {errBad}},
+			wantAsked: []string{"llama3.1"},
+			wantErr:   errBad,
+		},
+		{
+			name:      "should fall back when the model is missing",
+			settings:  map[string]any{"fallback_models": []string{"qwen2.5-coder", "phi3"}},
+			errs:      map[string][]error{"llama3.1": {errMissing}, "qwen2.5-coder": {errMissing}},
+			wantAsked: []string{"llama3.1", "qwen2.5-coder", "phi3"},
+			wantModel: "phi3",
+			wantNext:  "phi3",
+		},
+		{
+			name:      "should fall back when the model keeps failing",
+			settings:  map[string]any{"retries": 1, "fallback_models": []string{"phi3"}},
+			errs:      map[string][]error{"llama3.1": {errTemporary, errTemporary}},
+			wantAsked: []string{"llama3.1", "llama3.1", "phi3"},
+			wantModel: "phi3",
+			wantNext:  "phi3",
+		},
+		{
+			name:      "should report the models tried when none answers",
+			settings:  map[string]any{"fallback_models": []string{"qwen2.5-coder"}},
+			errs:      map[string][]error{"llama3.1": {errMissing}, "qwen2.5-coder": {errMissing}},
+			wantAsked: []string{"llama3.1", "qwen2.5-coder"},
+			wantErr:   provider.ErrModelNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("retry_backoff", "1ms")
+			for key, value := range tt.settings {
+				viper.Set(key, value)
+			}
+			defer viper.Reset()
+
+			llm := &scriptedProvider{errs: tt.errs}
+			g := &generator{ctx: context.Background(), llm: llm, chain: modelChain("llama3.1", config.GetFallbackModels())}
+
+			resp, err := g.send(provider.Prompt("llama3.1", "x"), nil)
+			if fmt.Sprint(llm.asked) != fmt.Sprint(tt.wantAsked) {
+				t.Errorf("models asked = %v, want %v", llm.asked, tt.wantAsked)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("send() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("send() error = %v", err)
+			}
+			if resp.Model != tt.wantModel {
+				t.Errorf("send() model = %q, want %q", resp.Model, tt.wantModel)
+			}
+
+			// The following requests go to the model that answered
+			llm.asked = nil
+			if _, err := g.send(provider.Prompt("llama3.1", "x"), nil); err != nil {
+				t.Fatalf("second send() error = %v", err)
+			}
+			if fmt.Sprint(llm.asked) != fmt.Sprint([]string{tt.wantNext}) {
+				t.Errorf("models asked next = %v, want [%s]", llm.asked, tt.wantNext)
+			}
+		})
+	}
+}
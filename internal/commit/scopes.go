@@ -0,0 +1,64 @@
+package commit
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dakoctba/cmt/internal/config"
+	"github.com/dakoctba/cmt/internal/git"
+	"github.com/dakoctba/cmt/internal/scope"
+	"github.com/dakoctba/cmt/internal/validator"
+)
+
+// resolveScopes finds the scopes of the staged files from the configured
+// scopes and the repository's workspace packages, and restricts the scope
+// of the message to them unless the commitlint rules already do
+func (g *generator) resolveScopes(root string) error {
+	var packages []scope.Package
+	if config.GetScopeDetect() {
+		manifests, err := git.ListFiles(g.ctx, scope.Manifests...)
+		if err != nil {
+			return err
+		}
+		// A broken manifest should not stop the message from being written
+		var problems []error
+		packages, problems = scope.Detect(root, manifests)
+		for _, err := range problems {
+			fmt.Fprintf(os.Stderr, "Skipping a workspace for scope detection: %v\n", err)
+		}
+	}
+	resolver, err := scope.New(config.GetScopes(), packages)
+	if err != nil {
+		return err
+	}
+
+	g.scopes = resolver.Resolve(g.paths)
+	if len(g.scopes.Scopes) == 0 {
+		return nil
+	}
+
+	multiple := config.GetScopeMultiple()
+	if len(g.scopes.Scopes) > 1 && multiple == scope.MultipleSplit {
+		suggestSplit(g.scopes)
+	}
+
+	if g.scopes.Scope(multiple) == "" {
+		if _, ok := g.rules["scope-empty"]; !ok {
+			g.rules["scope-empty"] = validator.Rule{Level: validator.Error}
+		}
+	} else if _, ok := g.rules["scope-enum"]; !ok {
+		g.rules["scope-enum"] = validator.Rule{Level: validator.Error, Value: g.scopes.Scopes}
+	}
+	return nil
+}
+
+// suggestSplit tells the user which files belong to which scope, so that
+// the change can be committed one scope at a time
+func suggestSplit(res scope.Result) {
+	fmt.Fprintf(os.Stderr, "The staged changes span the scopes %s; consider one commit per scope:\n", strings.Join(res.Scopes, ", "))
+	for _, s := range res.Scopes {
+		fmt.Fprintf(os.Stderr, "  %s: %s\n", s, strings.Join(res.Files[s], " "))
+	}
+	fmt.Fprintln(os.Stderr)
+}
@@ -0,0 +1,85 @@
+package commit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dakoctba/cmt/internal/config"
+	"github.com/dakoctba/cmt/internal/validator"
+	"github.com/spf13/viper"
+)
+
+func TestResolveScopes(t *testing.T) {
+	tests := []struct {
+		name      string
+		settings  map[string]any
+		rules     validator.Rules
+		paths     []string
+		message   string
+		wantScope string
+		wantValid bool
+	}{
+		{
+			name:      "should accept the scope of the changed files",
+			settings:  map[string]any{"scopes": map[string][]string{"api": {"services/api/"}}},
+			paths:     []string{"services/api/main.go"},
+			message:   "feat(api): add server",
+			wantScope: "api",
+			wantValid: true,
+		},
+		{
+			name:      "should reject another scope",
+			settings:  map[string]any{"scopes": map[string][]string{"api": {"services/api/"}}},
+			paths:     []string{"services/api/main.go"},
+			message:   "feat(server): add server",
+			wantScope: "api",
+		},
+		{
+			name:     "should reject a scope when leaving out several",
+			settings: map[string]any{"scopes": map[string][]string{"api": {"api/"}, "web": {"web/"}}, "scope_multiple": "omit"},
+			paths:    []string{"api/main.go", "web/index.ts"},
+			message:  "feat(api): add pages",
+		},
+		{
+			name:      "should keep the scopes of the commitlint rules",
+			settings:  map[string]any{"scopes": map[string][]string{"api": {"services/api/"}}},
+			rules:     validator.Rules{"scope-enum": {Level: validator.Error, Value: []string{"server"}}},
+			paths:     []string{"services/api/main.go"},
+			message:   "feat(server): add server",
+			wantScope: "api",
+			wantValid: true,
+		},
+		{
+			name:      "should not restrict the scope of unmatched files",
+			settings:  map[string]any{"scopes": map[string][]string{"api": {"services/api/"}}},
+			paths:     []string{"README.md"},
+			message:   "docs(readme): explain the setup",
+			wantValid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			for key, value := range tt.settings {
+				viper.Set(key, value)
+			}
+			defer viper.Reset()
+
+			rules := validator.Rules{}
+			for name, rule := range tt.rules {
+				rules[name] = rule
+			}
+			g := &generator{ctx: context.Background(), paths: tt.paths, rules: rules}
+			if err := g.resolveScopes(t.TempDir()); err != nil {
+				t.Fatalf("resolveScopes() error = %v", err)
+			}
+			if got := g.scopes.Scope(config.GetScopeMultiple()); got != tt.wantScope {
+				t.Errorf("scope = %q, want %q", got, tt.wantScope)
+			}
+			if got := g.rules.Validate(tt.message); got.Valid() != tt.wantValid {
+				t.Errorf("Validate(%q) = %v, want valid %v", tt.message, got.Violations, tt.wantValid)
+			}
+		})
+	}
+}
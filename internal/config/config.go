@@ -4,72 +4,486 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/dakoctba/cmt/internal/catalog"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
-// InitConfig initializes the configuration system
-func InitConfig(cfgFile, model string) {
-	if cfgFile != "" {
-		// Use config file from the flag
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Find home directory
-		home, err := os.UserHomeDir()
+// DefaultDiffBudget is the number of diff tokens sent to the model when no
+// budget is configured and its context window is unknown. It leaves room
+// for the prompt and the answer in Ollama's default 4096-token context
+// window.
+const DefaultDiffBudget = 3000
+
+// DefaultDiffMaxChunks is the most summarisation requests made for one diff
+const DefaultDiffMaxChunks = 40
+
+// DefaultStyleCommits is the number of recent commits sampled to learn the
+// repository's commit style
+const DefaultStyleCommits = 50
+
+// DefaultStyleExamples is the number of recent commit messages shown to the
+// model as examples
+const DefaultStyleExamples = 3
+
+// DefaultTimeout is the longest a single model request may take
+const DefaultTimeout = 5 * time.Minute
+
+// Load reads the configuration and returns the effective settings. Values
+// are layered, each layer overriding the previous one: built-in defaults,
+// the global config file (or cfgFile, when given), the repository's
+// .cmt.yaml, CMT_* environment variables and finally the command-line flags
+// bound with BindFlag. Nothing is written: a missing global config file
+// simply leaves the defaults in place, see CreateFile.
+func Load(cfgFile string) (*Config, error) {
+	viper.Reset()
+	origins = map[string]string{}
+	for key, flag := range flags {
+		if err := viper.BindPFlag(key, flag); err != nil {
+			return nil, err
+		}
+	}
+	setDefaults()
+
+	files := []string{cfgFile}
+	if cfgFile == "" {
+		var err error
+		if files, err = GlobalFiles(); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range files {
+		ok, err := mergeFile(file, ScopeGlobal)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			return nil, err
+		}
+		if !ok && cfgFile != "" {
+			return nil, fmt.Errorf("config file %s does not exist", cfgFile)
 		}
+	}
 
-		// Search config in home directory with name ".cmt" (without extension)
-		viper.AddConfigPath(home)
-		viper.SetConfigType("yaml")
-		viper.SetConfigName(".cmt")
+	if repoFile := RepoFile(); repoFile != "" {
+		if _, err := mergeFile(repoFile, ScopeRepo); err != nil {
+			return nil, err
+		}
 	}
 
-	// Set defaults
-	viper.SetDefault("model", "llama3.1")
+	mergeEnv()
+	return Current()
+}
 
-	// Bind model flag to config
-	if model != "" {
-		viper.Set("model", model)
+// setDefaults registers the built-in values with viper, so that every key
+// shows up in the effective settings
+func setDefaults() {
+	data, _ := yaml.Marshal(Defaults())
+	defaults := map[string]any{}
+	yaml.Unmarshal(data, &defaults)
+	for key, value := range defaults {
+		viper.SetDefault(key, value)
 	}
+}
 
-	// If a config file is found, read it in
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			// Config file not found, create it with defaults
-			createDefaultConfig()
-		} else {
-			// Config file was found but another error was produced
-			fmt.Fprintf(os.Stderr, "Error reading config file: %v\n", err)
-			os.Exit(1)
-		}
+// defaultFile is the content of a config file created by CreateFile
+const defaultFile = `# cmt configuration. Run "cmt config keys" for every setting and
+# "cmt config validate" after editing this file.
+model: llama3.1
+`
+
+// CreateFile writes a starter config file at path, creating its directory.
+// An existing file is only replaced when overwrite is set.
+func CreateFile(path string, overwrite bool) error {
+	if !overwrite && exists(path) {
+		return fmt.Errorf("config file %s already exists", path)
 	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(defaultFile), 0644)
 }
 
-func createDefaultConfig() {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+// GetModel returns the configured model
+func GetModel() string {
+	return current().Model
+}
+
+// GetBaseURL returns the configured server address. An empty value means
+// the provider's default is used (for Ollama, the OLLAMA_HOST environment
+// variable or the local server).
+func GetBaseURL() string {
+	return current().BaseURL
+}
+
+// GetProvider returns the name of the model server provider: ollama or
+// openai (any OpenAI-compatible server)
+func GetProvider() string {
+	return current().Provider
+}
+
+// GetAPIKeyEnv returns the name of the environment variable holding the
+// provider's API key
+func GetAPIKeyEnv() string {
+	return current().APIKeyEnv
+}
+
+// GetAPIKey returns the provider's API key, read from the environment
+// variable named by api_key_env so that it never lives in a config file
+func GetAPIKey() string {
+	return os.Getenv(GetAPIKeyEnv())
+}
+
+// GetStream reports whether model output should be streamed to the terminal
+func GetStream() bool {
+	return current().Stream
+}
+
+// Override sets a configuration value for the current run only, taking
+// precedence over the config file (used for command-line flags)
+func Override(key string, value any) {
+	viper.Set(key, value)
+	origins[key] = OriginFlag
+}
+
+// BindFlag makes a command-line flag override the configuration key when
+// the flag is given
+func BindFlag(key string, flag *pflag.Flag) error {
+	flags[key] = flag
+	return viper.BindPFlag(key, flag)
+}
+
+// Generation holds the options the model generates with. Nil and zero
+// values leave the server's defaults.
+type Generation struct {
+	Temperature *float64
+	TopP        *float64
+	TopK        *int
+	Seed        *int
+	NumCtx      int
+	NumPredict  int
+	Stop        []string
+	KeepAlive   string
+	System      string
+}
+
+// GetGeneration returns the generation options for model: the top-level
+// settings, overridden by the ones set in the model's block under "models".
+// On Ollama, the only server num_ctx is sent to, recommended models run
+// with the context window of their catalog entry unless num_ctx is set.
+func GetGeneration(model string) Generation {
+	g := generation(model)
+	if e, ok := catalog.Lookup(model); ok && g.NumCtx == 0 && GetProvider() == "ollama" {
+		g.NumCtx = e.Context
 	}
+	return g
+}
 
-	configPath := filepath.Join(home, ".cmt.yaml")
+func generation(model string) Generation {
+	cfg := current()
+	g := Generation{
+		Temperature: cfg.Temperature,
+		TopP:        cfg.TopP,
+		TopK:        cfg.TopK,
+		Seed:        cfg.Seed,
+		NumCtx:      cfg.NumCtx,
+		NumPredict:  cfg.NumPredict,
+		Stop:        cfg.Stop,
+		KeepAlive:   cfg.KeepAlive,
+		System:      cfg.System,
+	}
 
-	// Create default config
-	viper.Set("model", "llama3.1")
+	m, ok := cfg.Models[strings.ToLower(model)]
+	if !ok {
+		return g
+	}
+	if m.Temperature != nil {
+		g.Temperature = m.Temperature
+	}
+	if m.TopP != nil {
+		g.TopP = m.TopP
+	}
+	if m.TopK != nil {
+		g.TopK = m.TopK
+	}
+	if m.Seed != nil {
+		g.Seed = m.Seed
+	}
+	if m.NumCtx > 0 {
+		g.NumCtx = m.NumCtx
+	}
+	if m.NumPredict > 0 {
+		g.NumPredict = m.NumPredict
+	}
+	if len(m.Stop) > 0 {
+		g.Stop = m.Stop
+	}
+	if m.KeepAlive != "" {
+		g.KeepAlive = m.KeepAlive
+	}
+	if m.System != "" {
+		g.System = m.System
+	}
+	return g
+}
 
-	// Write config file
-	if err := viper.WriteConfigAs(configPath); err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating config file: %v\n", err)
-		os.Exit(1)
+// GetAutoCommit reports whether the generated message should be committed
+// without asking
+func GetAutoCommit() bool {
+	return current().Commit
+}
+
+// GetSignoff reports whether commits get a Signed-off-by trailer
+func GetSignoff() bool {
+	return current().Signoff
+}
+
+// GetGPGSign reports whether commits are GPG-signed
+func GetGPGSign() bool {
+	return current().GPGSign
+}
+
+// GetGPGKey returns the key used for signing; empty means git's default
+func GetGPGKey() string {
+	return current().GPGKey
+}
+
+// GetNoVerify reports whether commit hooks are bypassed
+func GetNoVerify() bool {
+	return current().NoVerify
+}
+
+// GetLintRetries returns how many times a message breaking the commitlint
+// rules is sent back to the model for correction
+func GetLintRetries() int {
+	return current().LintRetries
+}
+
+// GetCandidates returns how many messages are generated for the user to
+// choose from
+func GetCandidates() int {
+	return current().Candidates
+}
+
+// GetOutput returns the format the message is printed in, or an empty
+// string to review it in a terminal
+func GetOutput() string {
+	return current().Output
+}
+
+// GetTimeout returns the longest a single model request may take, or zero
+// for no limit
+func GetTimeout() time.Duration {
+	return current().Timeout
+}
+
+// GetRetries returns how many times a request failing with a temporary
+// server error is repeated
+func GetRetries() int {
+	return current().Retries
+}
+
+// GetRetryBackoff returns the wait before the first retry; each further
+// retry waits twice as long as the one before
+func GetRetryBackoff() time.Duration {
+	return current().RetryBackoff
+}
+
+// GetFallbackModels returns the models tried in order when the configured
+// one is missing or fails
+func GetFallbackModels() []string {
+	return current().FallbackModels
+}
+
+// GetAutoPull returns whether a model missing from the server is
+// downloaded without asking, or never; nil means the user is asked
+func GetAutoPull() *bool {
+	return current().AutoPull
+}
+
+// GetDiffStrategy returns how large diffs are handled: auto, full,
+// summarize or stat
+func GetDiffStrategy() string {
+	return current().DiffStrategy
+}
+
+// GetDiffBudget returns the number of diff tokens that may be sent to model,
+// taken from the model's block under "models" when it sets one. Without a
+// configured budget it is three quarters of the context window Ollama runs
+// the model with, see GetGeneration, leaving the rest to the prompt and the
+// answer. Other servers do not take num_ctx, so it does not size their
+// budget.
+func GetDiffBudget(model string) int {
+	cfg := current()
+	if budget := cfg.Models[strings.ToLower(model)].DiffBudget; budget > 0 {
+		return budget
+	}
+	if cfg.DiffBudget > 0 {
+		return cfg.DiffBudget
 	}
+	if n := GetGeneration(model).NumCtx; n > 0 && GetProvider() == "ollama" {
+		return n * 3 / 4
+	}
+	return DefaultDiffBudget
+}
 
-	fmt.Printf("Created default config file: %s\n", configPath)
+// GetDiffMaxChunks returns the most summarisation requests made for a
+// single diff before falling back to the outline strategy
+func GetDiffMaxChunks() int {
+	if chunks := current().DiffMaxChunks; chunks > 0 {
+		return chunks
+	}
+	return DefaultDiffMaxChunks
 }
 
-// GetModel returns the configured model
-func GetModel() string {
-	return viper.GetString("model")
+// GetIgnore returns extra gitignore-style patterns for files whose diffs
+// are left out of the prompt
+func GetIgnore() []string {
+	return current().Ignore
+}
+
+// GetIgnoreDefaults reports whether the built-in ignore patterns (lockfiles,
+// vendored and generated code) apply
+func GetIgnoreDefaults() bool {
+	return current().IgnoreDefaults
+}
+
+// GetRedact reports whether secrets and personal data are masked before
+// the diff is sent to the model
+func GetRedact() bool {
+	return current().Redact
+}
+
+// GetRedactPatterns returns the user-defined redaction patterns, by name
+func GetRedactPatterns() map[string]string {
+	return current().RedactPatterns
+}
+
+// GetShowRedactions reports whether redacted values are listed on stderr
+func GetShowRedactions() bool {
+	return current().ShowRedactions
+}
+
+// GetBlockSecrets reports whether cmt refuses to run when the staged
+// changes contain secrets
+func GetBlockSecrets() bool {
+	return current().BlockSecrets
+}
+
+// GetStyleCommits returns how many recent commits are sampled to learn the
+// repository's commit style; zero disables learning
+func GetStyleCommits() int {
+	return current().StyleCommits
+}
+
+// GetStyleExamples returns how many recent commit messages are shown to the
+// model as examples of the repository's style
+func GetStyleExamples() int {
+	return current().StyleExamples
+}
+
+// GetScopes returns the configured scopes, each mapped to the
+// gitignore-style patterns of its files
+func GetScopes() map[string][]string {
+	return current().Scopes
+}
+
+// GetScopeDetect reports whether scopes are derived from the packages of
+// Go, npm, pnpm and Cargo workspaces when no configured scope matches
+func GetScopeDetect() bool {
+	return current().ScopeDetect
+}
+
+// GetScopeMultiple returns how a change spanning several scopes is
+// described: join, omit or split
+func GetScopeMultiple() string {
+	return current().ScopeMultiple
+}
+
+// GetBranchPattern returns the regular expression reading the ticket ID and
+// branch type from branch names; empty means the built-in one
+func GetBranchPattern() string {
+	return current().BranchPattern
+}
+
+// GetBranchTypes returns the branch types mapped to the commit types they
+// suggest, on top of the built-in ones
+func GetBranchTypes() map[string]string {
+	return current().BranchTypes
+}
+
+// GetTicketFooter returns the template of the trailer added for the ticket
+// read from the branch name; empty adds none
+func GetTicketFooter() string {
+	return current().TicketFooter
+}
+
+// GetTicketPrefix returns the template of the subject prefix added for the
+// ticket read from the branch name; empty adds none
+func GetTicketPrefix() string {
+	return current().TicketPrefix
+}
+
+// GetLanguage returns the language commit messages are written in
+func GetLanguage() string {
+	return current().Language
+}
+
+// GetPromptTemplate returns the text of the commit prompt template, or an
+// empty string for the built-in one. A prompt_template value spanning
+// several lines or containing "{{" is the template itself; anything else is
+// the path of a file holding it, relative to the config file that set it.
+// The file named by a repository's .cmt.yaml must be inside the repository,
+// as its content is sent to the model server.
+func GetPromptTemplate() (string, error) {
+	value := current().PromptTemplate
+	if value == "" || strings.Contains(value, "\n") || strings.Contains(value, "{{") {
+		return value, nil
+	}
+
+	path := value
+	if origin := Origin("prompt_template"); origin == RepoFile() {
+		return readRepoTemplate(filepath.Dir(origin), path)
+	}
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[2:])
+	}
+	if origin := Origin("prompt_template"); !filepath.IsAbs(path) && filepath.IsAbs(origin) {
+		path = filepath.Join(filepath.Dir(origin), path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	return string(data), nil
+}
+
+// readRepoTemplate reads a prompt template file named by the repository's
+// config, refusing paths that lead out of the repository root, through
+// symbolic links too
+func readRepoTemplate(root, path string) (string, error) {
+	if strings.HasPrefix(path, "~") || filepath.IsAbs(path) {
+		return "", fmt.Errorf("prompt_template in %s must be a path inside the repository, not %s", RepoFileName, path)
+	}
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Join(root, path))
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	if rel, err := filepath.Rel(root, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("prompt_template in %s must be a path inside the repository, not %s", RepoFileName, path)
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	return string(data), nil
 }
@@ -5,136 +5,129 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/dakoctba/cmt/internal/git/gittest"
 	"github.com/spf13/viper"
 )
 
-func TestInitConfig(t *testing.T) {
+func TestLoad(t *testing.T) {
 	tests := []struct {
 		name      string
 		cfgFile   string
+		content   string
+		wantModel string
 		wantErr   bool
-		setupFn   func()
-		cleanupFn func()
 	}{
 		{
-			name:    "should initialize config with default values",
-			cfgFile: "",
-			wantErr: false,
-			setupFn: func() {
-				// Reset viper
-				viper.Reset()
-			},
-			cleanupFn: func() {
-				// Clean up after test
-				viper.Reset()
-			},
+			name:      "should load default values",
+			cfgFile:   "",
+			wantModel: "llama3.1",
+		},
+		{
+			name:      "should load a custom config file",
+			cfgFile:   "test_config.yaml",
+			content:   "model: test-model\n",
+			wantModel: "test-model",
+		},
+		{
+			name:    "should fail when the custom config file does not exist",
+			cfgFile: "missing.yaml",
+			wantErr: true,
 		},
 		{
-			name:    "should initialize config with custom config file",
+			name:    "should fail on an invalid config file",
 			cfgFile: "test_config.yaml",
-			wantErr: false,
-			setupFn: func() {
-				// Create test config file
-				testConfig := `model: test-model`
-				err := os.WriteFile("test_config.yaml", []byte(testConfig), 0644)
-				if err != nil {
-					t.Fatalf("Failed to create test config file: %v", err)
-				}
-				viper.Reset()
-			},
-			cleanupFn: func() {
-				// Clean up test files
-				os.Remove("test_config.yaml")
-				viper.Reset()
-			},
+			content: "streem: true\n",
+			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.setupFn != nil {
-				tt.setupFn()
-			}
-			defer func() {
-				if tt.cleanupFn != nil {
-					tt.cleanupFn()
-				}
-			}()
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("XDG_CONFIG_HOME", "")
+			dir := gittest.Chdir(t)
 
-			// This function doesn't return an error, so we just check if it panics
-			defer func() {
-				if r := recover(); r != nil && !tt.wantErr {
-					t.Errorf("InitConfig() panicked unexpectedly: %v", r)
+			cfgFile := tt.cfgFile
+			if cfgFile != "" {
+				cfgFile = filepath.Join(dir, cfgFile)
+			}
+			if tt.content != "" {
+				if err := os.WriteFile(cfgFile, []byte(tt.content), 0644); err != nil {
+					t.Fatalf("Failed to create test config file: %v", err)
 				}
-			}()
+			}
+			defer viper.Reset()
 
-			InitConfig(tt.cfgFile, "")
+			cfg, err := Load(cfgFile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if cfg.Model != tt.wantModel {
+				t.Errorf("Load() model = %v, want %v", cfg.Model, tt.wantModel)
+			}
 
-			// Check if default model is set
-			model := viper.GetString("model")
-			if model == "" {
-				t.Error("initConfig() should set default model")
+			// Loading never writes a config file
+			entries, err := os.ReadDir(home)
+			if err != nil {
+				t.Fatalf("Failed to read home directory: %v", err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("Load() should not create files in the home directory, found %v", entries)
 			}
 		})
 	}
 }
 
-func TestCreateDefaultConfig(t *testing.T) {
+func TestCreateFile(t *testing.T) {
 	tests := []struct {
 		name      string
+		existing  string
+		overwrite bool
 		wantErr   bool
-		setupFn   func()
-		cleanupFn func()
 	}{
 		{
-			name:    "should create default config file",
-			wantErr: false,
-			setupFn: func() {
-				// Reset viper
-				viper.Reset()
-				viper.Set("model", "test-model")
-			},
-			cleanupFn: func() {
-				// Clean up test files
-				home, err := os.UserHomeDir()
-				if err == nil {
-					configPath := filepath.Join(home, ".cmt.yaml")
-					os.Remove(configPath)
-				}
-				viper.Reset()
-			},
+			name: "should create the file and its directory",
+		},
+		{
+			name:     "should keep an existing file",
+			existing: "model: phi3\n",
+			wantErr:  true,
+		},
+		{
+			name:      "should replace an existing file when asked to",
+			existing:  "model: phi3\n",
+			overwrite: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.setupFn != nil {
-				tt.setupFn()
+			path := filepath.Join(t.TempDir(), "cmt", "config.yaml")
+			if tt.existing != "" {
+				os.MkdirAll(filepath.Dir(path), 0755)
+				os.WriteFile(path, []byte(tt.existing), 0644)
 			}
-			defer func() {
-				if tt.cleanupFn != nil {
-					tt.cleanupFn()
-				}
-			}()
-
-			// This function doesn't return an error, so we just check if it panics
-			defer func() {
-				if r := recover(); r != nil && !tt.wantErr {
-					t.Errorf("createDefaultConfig() panicked unexpectedly: %v", r)
-				}
-			}()
-
-			createDefaultConfig()
 
-			// Check if config file was created
-			home, err := os.UserHomeDir()
-			if err != nil {
-				t.Skipf("Cannot get home directory: %v", err)
+			err := CreateFile(path, tt.overwrite)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateFile() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
-			configPath := filepath.Join(home, ".cmt.yaml")
-			if _, err := os.Stat(configPath); os.IsNotExist(err) {
-				t.Error("createDefaultConfig() should create config file")
+			problems, err := ValidateFile(path, ScopeGlobal)
+			if err != nil || len(problems) > 0 {
+				t.Errorf("ValidateFile() = %v, %v, want a valid file", problems, err)
+			}
+			value, _, _ := Get(path, "model")
+			want := "llama3.1"
+			if tt.wantErr {
+				want = "phi3"
+			}
+			if value != want {
+				t.Errorf("model = %v, want %v", value, want)
 			}
 		})
 	}
@@ -263,3 +256,354 @@ func TestConfigPathResolution(t *testing.T) {
 		})
 	}
 }
+
+func TestLayeredConfig(t *testing.T) {
+	writeFile := func(t *testing.T, path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		legacy     string
+		xdg        string
+		repo       string
+		env        map[string]string
+		key        string
+		want       any
+		wantOrigin string
+	}{
+		{
+			name:       "should fall back to defaults",
+			legacy:     "stream: false\n",
+			key:        "lint_retries",
+			want:       2,
+			wantOrigin: OriginDefault,
+		},
+		{
+			name:       "should read the legacy global file",
+			legacy:     "model: legacy-model\n",
+			key:        "model",
+			want:       "legacy-model",
+			wantOrigin: "legacy",
+		},
+		{
+			name:       "should prefer the XDG file over the legacy one",
+			legacy:     "model: legacy-model\n",
+			xdg:        "model: xdg-model\n",
+			key:        "model",
+			want:       "xdg-model",
+			wantOrigin: "xdg",
+		},
+		{
+			name:       "should let the repository override the global files",
+			xdg:        "model: xdg-model\n",
+			repo:       "model: repo-model\n",
+			key:        "model",
+			want:       "repo-model",
+			wantOrigin: "repo",
+		},
+		{
+			name:       "should let the environment override files",
+			repo:       "diff_budget: 5000\n",
+			env:        map[string]string{"CMT_DIFF_BUDGET": "9000"},
+			key:        "diff_budget",
+			want:       "9000",
+			wantOrigin: "env CMT_DIFF_BUDGET",
+		},
+		{
+			name:       "should not let the repository pick the API key variable",
+			xdg:        "api_key_env: MY_KEY\n",
+			repo:       "api_key_env: AWS_SECRET_ACCESS_KEY\n",
+			key:        "api_key_env",
+			want:       "MY_KEY",
+			wantOrigin: "xdg",
+		},
+		{
+			name:       "should not let the repository send the API key to another server",
+			xdg:        "base_url: http://gpu-box.local:11434\n",
+			repo:       "provider: openai\nbase_url: https://attacker.example\n",
+			env:        map[string]string{"OPENAI_API_KEY": "sk-secret"},
+			key:        "base_url",
+			want:       "http://gpu-box.local:11434",
+			wantOrigin: "xdg",
+		},
+		{
+			name:       "should not let the repository pick the provider",
+			repo:       "provider: openai\nbase_url: https://attacker.example\n",
+			env:        map[string]string{"OPENAI_API_KEY": "sk-secret"},
+			key:        "provider",
+			want:       "ollama",
+			wantOrigin: OriginDefault,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("XDG_CONFIG_HOME", "")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			repo := gittest.Chdir(t)
+
+			paths := map[string]string{
+				"legacy": filepath.Join(home, ".cmt.yaml"),
+				"xdg":    filepath.Join(home, ".config", "cmt", "config.yaml"),
+				"repo":   filepath.Join(repo, RepoFileName),
+			}
+			for layer, content := range map[string]string{"legacy": tt.legacy, "xdg": tt.xdg, "repo": tt.repo} {
+				if content != "" {
+					writeFile(t, paths[layer], content)
+				}
+			}
+
+			defer viper.Reset()
+			if _, err := Load(""); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			if got := viper.Get(tt.key); got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.key, got, tt.want)
+			}
+			wantOrigin := tt.wantOrigin
+			if path, ok := paths[wantOrigin]; ok {
+				wantOrigin = path
+			}
+			if got := Origin(tt.key); got != wantOrigin {
+				t.Errorf("Origin(%q) = %v, want %v", tt.key, got, wantOrigin)
+			}
+		})
+	}
+}
+
+func TestGetGeneration(t *testing.T) {
+	const content = `temperature: 0.7
+seed: 42
+stop: [END]
+system: Be terse.
+models:
+  llama3.1:
+    temperature: 0
+    num_ctx: 16384
+`
+	tests := []struct {
+		name     string
+		model    string
+		provider string
+		check    func(g Generation) bool
+	}{
+		{
+			name:  "should use the top-level options",
+			model: "phi3",
+			check: func(g Generation) bool {
+				return *g.Temperature == 0.7 && *g.Seed == 42 && g.NumCtx == 0 && g.TopP == nil && g.System == "Be terse."
+			},
+		},
+		{
+			name:  "should let the model's block override them",
+			model: "llama3.1",
+			check: func(g Generation) bool {
+				return *g.Temperature == 0 && *g.Seed == 42 && g.NumCtx == 16384 && len(g.Stop) == 1
+			},
+		},
+		{
+			name:  "should run recommended models with their context window",
+			model: "qwen2.5-coder:7b",
+			check: func(g Generation) bool {
+				return g.NumCtx == 16384 && *g.Temperature == 0.7
+			},
+		},
+		{
+			name:     "should leave the context window to other servers",
+			model:    "qwen2.5-coder:7b",
+			provider: "openai",
+			check: func(g Generation) bool {
+				return g.NumCtx == 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("HOME", dir)
+			t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+			path := filepath.Join(dir, "config.yaml")
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			defer viper.Reset()
+
+			if _, err := Load(path); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if tt.provider != "" {
+				viper.Set("provider", tt.provider)
+			}
+			if g := GetGeneration(tt.model); !tt.check(g) {
+				t.Errorf("GetGeneration(%q) = %+v", tt.model, g)
+			}
+		})
+	}
+}
+
+func TestGetDiffBudget(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		model   string
+		want    int
+	}{
+		{
+			name:  "should fall back to the default for unknown models",
+			model: "phi3",
+			want:  DefaultDiffBudget,
+		},
+		{
+			name:  "should derive the budget from a recommended model's context",
+			model: "qwen2.5-coder:7b",
+			want:  12288,
+		},
+		{
+			name:    "should derive the budget from num_ctx",
+			content: "models:\n  phi3:\n    num_ctx: 4096\n",
+			model:   "phi3",
+			want:    3072,
+		},
+		{
+			name:    "should not size the budget from the catalog on other servers",
+			content: "provider: openai\n",
+			model:   "qwen2.5-coder:7b",
+			want:    DefaultDiffBudget,
+		},
+		{
+			name:    "should not size the budget from num_ctx on other servers",
+			content: "provider: openai\nnum_ctx: 32768\n",
+			model:   "phi3",
+			want:    DefaultDiffBudget,
+		},
+		{
+			name:    "should prefer a configured budget",
+			content: "diff_budget: 2000\n",
+			model:   "qwen2.5-coder:7b",
+			want:    2000,
+		},
+		{
+			name:    "should prefer the model's budget",
+			content: "diff_budget: 2000\nmodels:\n  qwen2.5-coder:7b:\n    diff_budget: 9000\n",
+			model:   "qwen2.5-coder:7b",
+			want:    9000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("HOME", dir)
+			t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+			path := filepath.Join(dir, "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			defer viper.Reset()
+
+			if _, err := Load(path); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if got := GetDiffBudget(tt.model); got != tt.want {
+				t.Errorf("GetDiffBudget(%q) = %d, want %d", tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPromptTemplate(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "id_rsa")
+	if err := os.WriteFile(secret, []byte("PRIVATE KEY"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		global  string
+		repo    string
+		link    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "should read a template file inside the repository",
+			repo: "prompt_template: .cmt/prompt.tmpl\n",
+			want: "{{.Diff}}",
+		},
+		{
+			name:   "should read any file named by the global config",
+			global: "prompt_template: " + secret + "\n",
+			want:   "PRIVATE KEY",
+		},
+		{
+			name:    "should not let the repository read files in the home directory",
+			repo:    "prompt_template: ~/.ssh/id_rsa\n",
+			wantErr: true,
+		},
+		{
+			name:    "should not let the repository read absolute paths",
+			repo:    "prompt_template: " + secret + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "should not let the repository read files above its root",
+			repo:    "prompt_template: ../outside.tmpl\n",
+			wantErr: true,
+		},
+		{
+			name:    "should not follow links out of the repository",
+			repo:    "prompt_template: prompt.tmpl\n",
+			link:    "prompt.tmpl",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("XDG_CONFIG_HOME", "")
+			repo := gittest.Chdir(t)
+			os.MkdirAll(filepath.Join(repo, ".cmt"), 0755)
+			os.WriteFile(filepath.Join(repo, ".cmt", "prompt.tmpl"), []byte("{{.Diff}}"), 0644)
+			os.WriteFile(filepath.Join(filepath.Dir(repo), "outside.tmpl"), []byte("PRIVATE KEY"), 0644)
+			if tt.link != "" {
+				if err := os.Symlink(secret, filepath.Join(repo, tt.link)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.global != "" {
+				os.MkdirAll(filepath.Join(home, ".config", "cmt"), 0755)
+				os.WriteFile(filepath.Join(home, ".config", "cmt", "config.yaml"), []byte(tt.global), 0644)
+			}
+			if tt.repo != "" {
+				os.WriteFile(filepath.Join(repo, RepoFileName), []byte(tt.repo), 0644)
+			}
+
+			defer viper.Reset()
+			if _, err := Load(""); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			got, err := GetPromptTemplate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetPromptTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetPromptTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
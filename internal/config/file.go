@@ -0,0 +1,437 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scope selects which config file a command reads or writes
+type Scope string
+
+const (
+	// ScopeGlobal is the user's config file
+	ScopeGlobal Scope = "global"
+
+	// ScopeRepo is the .cmt.yaml at the root of the current repository
+	ScopeRepo Scope = "repo"
+)
+
+// ScopePath returns the config file of the scope. The global file is the
+// XDG one unless only the legacy ~/.cmt.yaml exists.
+func ScopePath(scope Scope) (string, error) {
+	switch scope {
+	case ScopeRepo:
+		path := RepoFile()
+		if path == "" {
+			return "", fmt.Errorf("not in a Git repository, so there is no repository config")
+		}
+		return path, nil
+	case ScopeGlobal:
+		files, err := GlobalFiles()
+		if err != nil {
+			return "", err
+		}
+		legacy, xdg := files[0], files[1]
+		if !exists(xdg) && exists(legacy) {
+			return legacy, nil
+		}
+		return xdg, nil
+	}
+	return "", fmt.Errorf("unknown config scope %q", scope)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Problem is an error found in a config file
+type Problem struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", p.Path, p.Line, p.Column, p.Message)
+}
+
+// Problems is a list of problems, usable as an error
+type Problems []Problem
+
+func (p Problems) Error() string {
+	lines := make([]string, len(p))
+	for i, problem := range p {
+		lines[i] = problem.String()
+	}
+	return "invalid configuration:\n" + strings.Join(lines, "\n")
+}
+
+// ValidateFile checks a config file against the schema: unknown keys, values
+// of the wrong type, invalid enumerations and, in a repository config, keys
+// that may only be set globally. A missing file has no problems.
+func ValidateFile(path string, scope Scope) (Problems, error) {
+	doc, err := readNode(path)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	return validateNode(path, doc, scope), nil
+}
+
+func validateNode(path string, doc *yaml.Node, scope Scope) Problems {
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return Problems{{Path: path, Line: root.Line, Column: root.Column, Message: "expected a mapping of keys to values"}}
+	}
+
+	var problems Problems
+	add := func(n *yaml.Node, format string, args ...any) {
+		problems = append(problems, Problem{Path: path, Line: n.Line, Column: n.Column, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var walk func(keys []Key, mapping *yaml.Node, prefix string)
+	walk = func(keys []Key, mapping *yaml.Node, prefix string) {
+		for i := 0; i+1 < len(mapping.Content); i += 2 {
+			name, value := mapping.Content[i], mapping.Content[i+1]
+			k, ok := lookup(keys, name.Value)
+			if !ok {
+				if hint := suggest(keys, name.Value); hint != "" {
+					add(name, "unknown key %q, did you mean %q?", prefix+name.Value, prefix+hint)
+				} else {
+					add(name, "unknown key %q", prefix+name.Value)
+				}
+				continue
+			}
+			if prefix == "" && scope == ScopeRepo && restricted(k.Name) {
+				add(name, "%s can only be set in the global config", k.Name)
+				continue
+			}
+
+			if k.Nested != nil {
+				if value.Kind != yaml.MappingNode {
+					add(value, "%s%s must be a mapping of names to settings", prefix, k.Name)
+					continue
+				}
+				for j := 0; j+1 < len(value.Content); j += 2 {
+					entry, block := value.Content[j], value.Content[j+1]
+					if block.Kind != yaml.MappingNode {
+						add(block, "%s%s.%s must be a mapping of settings", prefix, k.Name, entry.Value)
+						continue
+					}
+					walk(k.Nested, block, prefix+k.Name+"."+entry.Value+".")
+				}
+				continue
+			}
+
+			target := reflect.New(k.Type)
+			if err := value.Decode(target.Interface()); err != nil {
+				add(value, "invalid value for %s%s, expected %s", prefix, k.Name, k.TypeName())
+				continue
+			}
+			if err := k.check(target.Elem().Interface()); err != nil {
+				add(value, "%v", err)
+			}
+		}
+	}
+	walk(Keys(), root, "")
+	return problems
+}
+
+func restricted(key string) bool {
+	for _, k := range repoRestrictedKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// readNode parses a YAML file, returning nil when it does not exist
+func readNode(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %v", path, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	// Empty files, or files holding only comments, have no mapping yet
+	if doc.Kind != yaml.DocumentNode {
+		return emptyDocument(), nil
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Tag == "!!null" {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	return &doc, nil
+}
+
+func emptyDocument() *yaml.Node {
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+}
+
+func writeNode(path string, doc *yaml.Node) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode config file %s: %v", path, err)
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// keyPath splits a dotted key into its YAML path and resolves its schema
+// entry. Entries of map keys are addressed as redact_patterns.<name> and
+// models.<model>.<setting>; model names may themselves contain dots.
+func keyPath(name string) ([]string, Key, error) {
+	top, rest, _ := strings.Cut(name, ".")
+	k, ok := LookupKey(top)
+	if !ok {
+		if hint := suggest(Keys(), top); hint != "" {
+			return nil, Key{}, fmt.Errorf("unknown key %q, did you mean %q?", top, hint)
+		}
+		return nil, Key{}, fmt.Errorf("unknown key %q", top)
+	}
+
+	switch {
+	case k.Nested != nil:
+		i := strings.LastIndex(rest, ".")
+		if i <= 0 {
+			return nil, Key{}, fmt.Errorf("%s settings are addressed as %s.<name>.<setting>", top, top)
+		}
+		entry, field := rest[:i], rest[i+1:]
+		nested, ok := lookup(k.Nested, field)
+		if !ok {
+			return nil, Key{}, fmt.Errorf("unknown key %q in %s.%s", field, top, entry)
+		}
+		return []string{top, entry, field}, nested, nil
+	case k.Type.Kind() == reflect.Map:
+		if rest == "" {
+			return nil, Key{}, fmt.Errorf("%s entries are addressed as %s.<name>", top, top)
+		}
+		return []string{top, rest}, Key{Name: name, Type: k.Type.Elem()}, nil
+	case rest != "":
+		return nil, Key{}, fmt.Errorf("%s has no nested keys", top)
+	}
+	return []string{top}, k, nil
+}
+
+// parseValue converts a command-line value to the key's type. Lists are
+// comma-separated.
+func parseValue(k Key, raw string) (any, error) {
+	var value any
+	kind := k.Type.Kind()
+	if kind == reflect.Ptr {
+		kind = k.Type.Elem().Kind()
+	}
+	switch kind {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s, expected true or false", raw, k.Name)
+		}
+		value = b
+	case reflect.Int64:
+		if _, err := time.ParseDuration(raw); err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s, expected a duration such as 90s or 5m", raw, k.Name)
+		}
+		value = raw
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s, expected a number", raw, k.Name)
+		}
+		value = n
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s, expected a number", raw, k.Name)
+		}
+		value = f
+	case reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value = items
+	default:
+		value = raw
+	}
+	return value, k.check(value)
+}
+
+// Get returns the value of key in the config file at path
+func Get(path, key string) (any, bool, error) {
+	keys, _, err := keyPath(key)
+	if err != nil {
+		return nil, false, err
+	}
+	doc, err := readNode(path)
+	if err != nil || doc == nil {
+		return nil, false, err
+	}
+
+	node := doc.Content[0]
+	for _, name := range keys {
+		if node = child(node, name); node == nil {
+			return nil, false, nil
+		}
+	}
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set writes key in the config file at path, creating the file if needed
+// and keeping its comments and layout
+func Set(path, key, raw string, scope Scope) error {
+	keys, k, err := keyPath(key)
+	if err != nil {
+		return err
+	}
+	if scope == ScopeRepo && restricted(keys[0]) {
+		return fmt.Errorf("%s can only be set in the global config", keys[0])
+	}
+	value, err := parseValue(k, raw)
+	if err != nil {
+		return err
+	}
+
+	doc, err := readNode(path)
+	if err != nil {
+		return err
+	}
+	if doc == nil {
+		doc = emptyDocument()
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("config file %s is not a mapping of keys to values", path)
+	}
+
+	node := doc.Content[0]
+	for _, name := range keys[:len(keys)-1] {
+		next := child(node, name)
+		if next == nil || next.Kind != yaml.MappingNode {
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			setChild(node, name, next)
+		}
+		node = next
+	}
+
+	var encoded yaml.Node
+	if err := encoded.Encode(value); err != nil {
+		return err
+	}
+	setChild(node, keys[len(keys)-1], &encoded)
+	return writeNode(path, doc)
+}
+
+// Unset removes key from the config file at path, dropping blocks it leaves
+// empty. It reports whether the key was set.
+func Unset(path, key string) (bool, error) {
+	keys, _, err := keyPath(key)
+	if err != nil {
+		return false, err
+	}
+	doc, err := readNode(path)
+	if err != nil || doc == nil {
+		return false, err
+	}
+
+	parents := []*yaml.Node{doc.Content[0]}
+	for _, name := range keys[:len(keys)-1] {
+		next := child(parents[len(parents)-1], name)
+		if next == nil {
+			return false, nil
+		}
+		parents = append(parents, next)
+	}
+	if !removeChild(parents[len(parents)-1], keys[len(keys)-1]) {
+		return false, nil
+	}
+	for i := len(parents) - 1; i > 0 && len(parents[i].Content) == 0; i-- {
+		removeChild(parents[i-1], keys[i-1])
+	}
+	return true, writeNode(path, doc)
+}
+
+// FileSettings returns the values set in the config file at path, sorted by
+// key
+func FileSettings(path string) ([]Setting, error) {
+	doc, err := readNode(path)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	settings := map[string]any{}
+	if err := doc.Decode(&settings); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+
+	values := flatten("", settings)
+	out := make([]Setting, 0, len(values))
+	for _, key := range sortedKeys(values) {
+		out = append(out, Setting{Key: key, Value: values[key], Origin: path})
+	}
+	return out, nil
+}
+
+func child(mapping *yaml.Node, name string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == name {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setChild(mapping *yaml.Node, name string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == name {
+			value.HeadComment, value.LineComment = mapping.Content[i+1].HeadComment, mapping.Content[i+1].LineComment
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, value)
+}
+
+func removeChild(mapping *yaml.Node, name string) bool {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == name {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
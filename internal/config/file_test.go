@@ -0,0 +1,256 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestValidateFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		scope   Scope
+		want    []string
+	}{
+		{
+			name:    "should accept a valid file",
+			content: "model: llama3.1\nstream: false\nmodels:\n  llama3.1:\n    diff_budget: 8000\n",
+			scope:   ScopeGlobal,
+		},
+		{
+			name:    "should accept an empty file",
+			content: "# nothing here yet\n",
+			scope:   ScopeGlobal,
+		},
+		{
+			name:    "should reject unknown keys with a suggestion",
+			content: "model: llama3.1\nstreem: true\n",
+			scope:   ScopeGlobal,
+			want:    []string{`:2:1: unknown key "streem", did you mean "stream"?`},
+		},
+		{
+			name:    "should reject values of the wrong type",
+			content: "lint_retries: many\n",
+			scope:   ScopeGlobal,
+			want:    []string{":1:15: invalid value for lint_retries, expected int"},
+		},
+		{
+			name:    "should reject invalid durations",
+			content: "timeout: soon\n",
+			scope:   ScopeGlobal,
+			want:    []string{":1:10: invalid value for timeout, expected duration"},
+		},
+		{
+			name:    "should reject values outside the enum",
+			content: "diff_strategy: everything\n",
+			scope:   ScopeGlobal,
+			want:    []string{`:1:16: invalid value "everything" for diff_strategy, expected one of auto, full, summarize, stat`},
+		},
+		{
+			name:    "should reject unknown keys in model blocks",
+			content: "models:\n  phi3:\n    diff_budgte: 1\n",
+			scope:   ScopeGlobal,
+			want:    []string{`:3:5: unknown key "models.phi3.diff_budgte", did you mean "models.phi3.diff_budget"?`},
+		},
+		{
+			name:    "should reject global-only keys in the repository config",
+			content: "api_key_env: AWS_SECRET_ACCESS_KEY\n",
+			scope:   ScopeRepo,
+			want:    []string{":1:1: api_key_env can only be set in the global config"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+
+			problems, err := ValidateFile(path, tt.scope)
+			if err != nil {
+				t.Fatalf("ValidateFile() error = %v", err)
+			}
+			if len(problems) != len(tt.want) {
+				t.Fatalf("ValidateFile() = %v, want %d problem(s)", problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got := problems[i].String(); !strings.Contains(got, want) {
+					t.Errorf("problem %d = %q, want it to contain %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetAndUnset(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		key     string
+		value   string
+		scope   Scope
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "should create the file",
+			key:   "model",
+			value: "qwen2.5-coder",
+			scope: ScopeGlobal,
+			want:  "model: qwen2.5-coder\n",
+		},
+		{
+			name:    "should keep comments when replacing a value",
+			content: "# my settings\nmodel: llama3.1 # fast enough\nstream: true\n",
+			key:     "model",
+			value:   "phi3",
+			scope:   ScopeGlobal,
+			want:    "# my settings\nmodel: phi3 # fast enough\nstream: true\n",
+		},
+		{
+			name:  "should write model blocks for names containing dots",
+			key:   "models.llama3.1.diff_budget",
+			value: "8000",
+			scope: ScopeGlobal,
+			want:  "models:\n  llama3.1:\n    diff_budget: 8000\n",
+		},
+		{
+			name:  "should split lists on commas",
+			key:   "ignore",
+			value: "*.snap, docs/api/",
+			scope: ScopeGlobal,
+			want:  "ignore:\n  - '*.snap'\n  - docs/api/\n",
+		},
+		{
+			name:  "should write durations as given",
+			key:   "timeout",
+			value: "90s",
+			scope: ScopeGlobal,
+			want:  "timeout: 90s\n",
+		},
+		{
+			name:    "should reject unknown keys",
+			key:     "streem",
+			value:   "true",
+			scope:   ScopeGlobal,
+			wantErr: true,
+		},
+		{
+			name:    "should reject values outside the enum",
+			key:     "provider",
+			value:   "anthropic",
+			scope:   ScopeGlobal,
+			wantErr: true,
+		},
+		{
+			name:    "should reject global-only keys in the repository config",
+			key:     "api_key_env",
+			value:   "GITHUB_TOKEN",
+			scope:   ScopeRepo,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cmt", "config.yaml")
+			if tt.content != "" {
+				os.MkdirAll(filepath.Dir(path), 0755)
+				if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+					t.Fatalf("Failed to write config file: %v", err)
+				}
+			}
+
+			err := Set(path, tt.key, tt.value, tt.scope)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read config file: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("config file = %q, want %q", data, tt.want)
+			}
+
+			if _, ok, err := Get(path, tt.key); err != nil || !ok {
+				t.Errorf("Get(%q) = _, %v, %v, want the value to be set", tt.key, ok, err)
+			}
+
+			removed, err := Unset(path, tt.key)
+			if err != nil || !removed {
+				t.Fatalf("Unset(%q) = %v, %v, want true", tt.key, removed, err)
+			}
+			if _, ok, _ := Get(path, tt.key); ok {
+				t.Errorf("Get(%q) found the key after Unset", tt.key)
+			}
+			if data, _ := os.ReadFile(path); strings.Contains(string(data), "models:") {
+				t.Errorf("Unset() should drop emptied blocks, got %q", data)
+			}
+		})
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]any
+		check   func(*Config) bool
+		wantErr bool
+	}{
+		{
+			name:   "should use the defaults",
+			values: map[string]any{},
+			check: func(c *Config) bool {
+				return c.Provider == "ollama" && c.Stream && c.LintRetries == 2
+			},
+		},
+		{
+			name:   "should convert environment strings",
+			values: map[string]any{"diff_budget": "6000", "stream": "false", "ignore": "*.snap,*.golden"},
+			check: func(c *Config) bool {
+				return c.DiffBudget == 6000 && !c.Stream && len(c.Ignore) == 2
+			},
+		},
+		{
+			name:   "should decode model blocks",
+			values: map[string]any{"models": map[string]any{"llama3.1": map[string]any{"diff_budget": 8000}}},
+			check: func(c *Config) bool {
+				return c.Models["llama3.1"].DiffBudget == 8000
+			},
+		},
+		{
+			name:    "should reject unknown providers",
+			values:  map[string]any{"provider": "anthropic"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			defer viper.Reset()
+			setDefaults()
+			for key, value := range tt.values {
+				viper.Set(key, value)
+			}
+
+			cfg, err := Current()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Current() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !tt.check(cfg) {
+				t.Errorf("Current() = %+v", cfg)
+			}
+		})
+	}
+}
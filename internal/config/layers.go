@@ -0,0 +1,187 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dakoctba/cmt/internal/git"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// RepoFileName is the per-repository config file, read from the root of
+// the repository cmt runs in
+const RepoFileName = ".cmt.yaml"
+
+// EnvPrefix prefixes the environment variables overriding config keys,
+// e.g. CMT_MODEL or CMT_DIFF_BUDGET
+const EnvPrefix = "CMT_"
+
+// Origins reported for values that do not come from a file
+const (
+	OriginDefault = "default"
+	OriginFlag    = "flag"
+)
+
+// repoRestrictedKeys cannot be set by a repository's .cmt.yaml: a cloned
+// repository must not be able to choose which environment variable is sent
+// to the model server as an API key, nor which server the key and the
+// staged changes are sent to
+var repoRestrictedKeys = []string{"api_key_env", "provider", "base_url"}
+
+var (
+	// origins maps each key set by a layer to where it was set
+	origins = map[string]string{}
+
+	// flags are the command-line flags bound to keys
+	flags = map[string]*pflag.Flag{}
+)
+
+// GlobalFiles returns the global config files in the order they are read,
+// later ones winning: the legacy ~/.cmt.yaml, then the XDG config file
+func GlobalFiles() ([]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return []string{filepath.Join(home, ".cmt.yaml"), XDGFile(home)}, nil
+}
+
+// XDGFile returns $XDG_CONFIG_HOME/cmt/config.yaml, defaulting to
+// ~/.config/cmt/config.yaml
+func XDGFile(home string) string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "cmt", "config.yaml")
+}
+
+// RepoFile returns the .cmt.yaml at the root of the current repository, or
+// an empty string outside a repository. Finding the root is a local lookup
+// that is not worth cancelling, so it runs without a deadline.
+func RepoFile() string {
+	root, err := git.RepoRoot(context.Background())
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(root, RepoFileName)
+}
+
+// mergeFile reads a YAML config file over the current settings, rejecting
+// it when it does not match the schema. It reports whether the file exists.
+func mergeFile(path string, scope Scope) (bool, error) {
+	doc, err := readNode(path)
+	if err != nil || doc == nil {
+		return false, err
+	}
+
+	var problems Problems
+	for _, p := range validateNode(path, doc, scope) {
+		// A shared repository config setting a global-only key should not
+		// stop cmt from working: ignore the key and say so
+		if scope == ScopeRepo && strings.HasSuffix(p.Message, "can only be set in the global config") {
+			fmt.Fprintf(os.Stderr, "Ignoring %s\n", p)
+			continue
+		}
+		problems = append(problems, p)
+	}
+	if len(problems) > 0 {
+		return true, problems
+	}
+
+	settings := map[string]any{}
+	if err := doc.Decode(&settings); err != nil {
+		return true, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	if scope == ScopeRepo {
+		for _, key := range repoRestrictedKeys {
+			delete(settings, key)
+		}
+	}
+
+	if err := viper.MergeConfigMap(settings); err != nil {
+		return true, fmt.Errorf("failed to merge config file %s: %v", path, err)
+	}
+	for key := range flatten("", settings) {
+		origins[key] = path
+	}
+	return true, nil
+}
+
+// mergeEnv applies CMT_* environment variables. They are bound rather than
+// set so that flags still take precedence.
+func mergeEnv() {
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(name, EnvPrefix) || name == EnvPrefix {
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		key := strings.ToLower(strings.TrimPrefix(name, EnvPrefix))
+		viper.BindEnv(key, name)
+		origins[key] = "env " + name
+	}
+}
+
+// Origin returns where the effective value of key comes from: "default", a
+// config file path, "env CMT_..." or "flag --..."
+func Origin(key string) string {
+	if f, ok := flags[key]; ok && f.Changed {
+		return "flag --" + f.Name
+	}
+	if origin, ok := origins[key]; ok {
+		return origin
+	}
+	return OriginDefault
+}
+
+// Setting is an effective configuration value
+type Setting struct {
+	Key    string
+	Value  any
+	Origin string
+}
+
+// Settings returns every effective value, sorted by key. Nested blocks such
+// as models are flattened into dotted keys.
+func Settings() []Setting {
+	values := flatten("", viper.AllSettings())
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	settings := make([]Setting, 0, len(keys))
+	for _, key := range keys {
+		settings = append(settings, Setting{Key: key, Value: values[key], Origin: Origin(key)})
+	}
+	return settings
+}
+
+// flatten turns nested maps into dotted keys
+func flatten(prefix string, settings map[string]any) map[string]any {
+	out := map[string]any{}
+	for key, value := range settings {
+		key = strings.ToLower(key)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]any); ok && len(nested) > 0 {
+			for k, v := range flatten(key, nested) {
+				out[k] = v
+			}
+			continue
+		}
+		out[key] = value
+	}
+	return out
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// Config is the configuration schema: every key a config file may set is a
+// field here, named by its yaml tag. Fields with an enum tag only accept the
+// listed values.
+type Config struct {
+	Model     string `yaml:"model" doc:"model used to write commit messages"`
+	Provider  string `yaml:"provider" enum:"ollama,openai" doc:"model server API (global config only)"`
+	BaseURL   string `yaml:"base_url" doc:"model server address; empty uses the provider default (global config only)"`
+	APIKeyEnv string `yaml:"api_key_env" doc:"environment variable holding the API key (global config only)"`
+	Stream    bool   `yaml:"stream" doc:"stream tokens to the terminal as they arrive"`
+
+	Temperature *float64 `yaml:"temperature" doc:"sampling temperature; unset uses the model's default"`
+	TopP        *float64 `yaml:"top_p" doc:"nucleus sampling probability mass"`
+	TopK        *int     `yaml:"top_k" doc:"number of most likely tokens sampled from (Ollama only)"`
+	Seed        *int     `yaml:"seed" doc:"random seed, for reproducible messages"`
+	NumCtx      int      `yaml:"num_ctx" doc:"context window in tokens (Ollama only); 0 uses the window of recommended models (see cmt models recommend), else the model's default"`
+	NumPredict  int      `yaml:"num_predict" doc:"most tokens generated per request; 0 is unlimited"`
+	Stop        []string `yaml:"stop" doc:"sequences that end generation"`
+	KeepAlive   string   `yaml:"keep_alive" doc:"how long the model stays loaded after a request, e.g. 10m (Ollama only)"`
+	System      string   `yaml:"system" doc:"system prompt sent before the commit prompt"`
+
+	Commit   bool   `yaml:"commit" doc:"commit with the generated message without asking"`
+	Signoff  bool   `yaml:"signoff" doc:"add a Signed-off-by trailer"`
+	GPGSign  bool   `yaml:"gpg_sign" doc:"GPG-sign commits"`
+	GPGKey   string `yaml:"gpg_key" doc:"key used for signing; empty uses git's default"`
+	NoVerify bool   `yaml:"no_verify" doc:"bypass the pre-commit and commit-msg hooks"`
+
+	LintRetries int `yaml:"lint_retries" doc:"times a message breaking the commit rules is sent back to the model"`
+	Candidates  int `yaml:"candidates" doc:"messages generated to choose from"`
+
+	Output string `yaml:"output" doc:"print the message instead of asking: text, json, shell or raw"`
+
+	Timeout time.Duration `yaml:"timeout" doc:"longest a model request may take, e.g. 2m; 0 waits forever"`
+
+	Retries        int           `yaml:"retries" doc:"times a request failing with a temporary server error is repeated"`
+	RetryBackoff   time.Duration `yaml:"retry_backoff" doc:"wait before the first retry, doubled for each one after it"`
+	FallbackModels []string      `yaml:"fallback_models" doc:"models tried in order when the model is missing or keeps failing"`
+	AutoPull       *bool         `yaml:"auto_pull" doc:"download a missing model without asking (true) or never (false); unset asks"`
+
+	StyleCommits  int `yaml:"style_commits" doc:"recent commits sampled to learn the repository's commit style; 0 disables"`
+	StyleExamples int `yaml:"style_examples" doc:"recent commit messages shown to the model as examples"`
+
+	Scopes        map[string][]string `yaml:"scopes" doc:"scope names mapped to gitignore-style patterns of their files"`
+	ScopeDetect   bool                `yaml:"scope_detect" doc:"derive scopes from Go modules and npm, pnpm and Cargo workspaces"`
+	ScopeMultiple string              `yaml:"scope_multiple" enum:"join,omit,split" doc:"how a change spanning several scopes is described"`
+
+	BranchPattern string            `yaml:"branch_pattern" doc:"regular expression reading the type and ticket named groups from branch names"`
+	BranchTypes   map[string]string `yaml:"branch_types" doc:"branch types mapped to the commit type they suggest, e.g. feature: feat"`
+	TicketFooter  string            `yaml:"ticket_footer" doc:"trailer added for the branch's ticket, a template such as \"Refs: {{.Ticket}}\"; empty disables"`
+	TicketPrefix  string            `yaml:"ticket_prefix" doc:"subject prefix added for the branch's ticket, a template such as \"{{.Ticket}} \""`
+
+	PromptTemplate string `yaml:"prompt_template" doc:"commit prompt template, inline or a file path; empty uses the built-in one"`
+	Language       string `yaml:"language" doc:"language commit messages are written in"`
+
+	DiffStrategy  string `yaml:"diff_strategy" enum:"auto,full,summarize,stat" doc:"how diffs over the budget are handled"`
+	DiffBudget    int    `yaml:"diff_budget" doc:"diff tokens sent to the model; 0 derives it from the context window"`
+	DiffMaxChunks int    `yaml:"diff_max_chunks" doc:"summarisation requests before falling back to stat"`
+
+	Ignore         []string `yaml:"ignore" doc:"gitignore-style patterns of files whose diffs are left out"`
+	IgnoreDefaults bool     `yaml:"ignore_defaults" doc:"leave lockfiles, vendored and generated code out"`
+
+	Redact         bool              `yaml:"redact" doc:"mask secrets and personal data before sending the diff"`
+	RedactPatterns map[string]string `yaml:"redact_patterns" doc:"extra redaction regular expressions, by name"`
+	ShowRedactions bool              `yaml:"show_redactions" doc:"list redacted values on stderr"`
+	BlockSecrets   bool              `yaml:"block_secrets" doc:"refuse to run when the staged changes contain secrets"`
+
+	Models map[string]ModelConfig `yaml:"models" doc:"per-model settings, by model name"`
+}
+
+// ModelConfig holds the settings that can differ per model. Unset
+// generation options fall back to the top-level ones.
+type ModelConfig struct {
+	DiffBudget int `yaml:"diff_budget" doc:"diff tokens sent to this model"`
+
+	Temperature *float64 `yaml:"temperature" doc:"sampling temperature for this model"`
+	TopP        *float64 `yaml:"top_p" doc:"nucleus sampling probability mass for this model"`
+	TopK        *int     `yaml:"top_k" doc:"number of most likely tokens sampled from for this model"`
+	Seed        *int     `yaml:"seed" doc:"random seed for this model"`
+	NumCtx      int      `yaml:"num_ctx" doc:"context window of this model in tokens"`
+	NumPredict  int      `yaml:"num_predict" doc:"most tokens this model generates per request"`
+	Stop        []string `yaml:"stop" doc:"sequences that end generation for this model"`
+	KeepAlive   string   `yaml:"keep_alive" doc:"how long this model stays loaded after a request"`
+	System      string   `yaml:"system" doc:"system prompt for this model"`
+}
+
+// Defaults returns the built-in configuration
+func Defaults() Config {
+	return Config{
+		Model:          "llama3.1",
+		Provider:       "ollama",
+		APIKeyEnv:      "OPENAI_API_KEY",
+		Stream:         true,
+		LintRetries:    2,
+		Candidates:     1,
+		Timeout:        DefaultTimeout,
+		Retries:        2,
+		RetryBackoff:   time.Second,
+		StyleCommits:   DefaultStyleCommits,
+		StyleExamples:  DefaultStyleExamples,
+		ScopeDetect:    true,
+		ScopeMultiple:  "join",
+		TicketFooter:   "Refs: {{.Ticket}}",
+		Language:       "English",
+		DiffStrategy:   "auto",
+		DiffMaxChunks:  DefaultDiffMaxChunks,
+		IgnoreDefaults: true,
+		Redact:         true,
+	}
+}
+
+// Key describes a configuration key
+type Key struct {
+	Name string
+	Doc  string
+	Enum []string
+	Type reflect.Type
+
+	// Nested describes the fields of each entry of a map of blocks, such
+	// as models
+	Nested []Key
+}
+
+// Keys returns the schema keys in declaration order
+func Keys() []Key {
+	return keysOf(reflect.TypeOf(Config{}))
+}
+
+func keysOf(t reflect.Type) []Key {
+	keys := make([]Key, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := Key{Name: f.Tag.Get("yaml"), Doc: f.Tag.Get("doc"), Type: f.Type}
+		if enum := f.Tag.Get("enum"); enum != "" {
+			key.Enum = strings.Split(enum, ",")
+		}
+		if f.Type.Kind() == reflect.Map && f.Type.Elem().Kind() == reflect.Struct {
+			key.Nested = keysOf(f.Type.Elem())
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// LookupKey returns the schema key with the given name
+func LookupKey(name string) (Key, bool) {
+	return lookup(Keys(), name)
+}
+
+func lookup(keys []Key, name string) (Key, bool) {
+	for _, k := range keys {
+		if k.Name == name {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+// TypeName describes the values the key accepts
+func (k Key) TypeName() string {
+	if len(k.Enum) > 0 {
+		return strings.Join(k.Enum, "|")
+	}
+	kind := k.Type.Kind()
+	if kind == reflect.Ptr {
+		kind = k.Type.Elem().Kind()
+	}
+	switch kind {
+	case reflect.Slice:
+		return "list"
+	case reflect.Map:
+		return "map"
+	case reflect.Float64:
+		return "number"
+	}
+	if k.Type == durationType {
+		return "duration"
+	}
+	return kind.String()
+}
+
+// check validates an enumerated value
+func (k Key) check(value any) error {
+	if len(k.Enum) == 0 {
+		return nil
+	}
+	s := fmt.Sprint(value)
+	for _, allowed := range k.Enum {
+		if s == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid value %q for %s, expected one of %s", s, k.Name, strings.Join(k.Enum, ", "))
+}
+
+// suggest returns the known key closest to an unknown one, if any is close
+func suggest(keys []Key, name string) string {
+	best, bestDistance := "", 3
+	for _, k := range keys {
+		if d := distance(name, k.Name); d < bestDistance {
+			best, bestDistance = k.Name, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein distance between a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// Current returns the effective configuration, decoded from the layered
+// settings over the built-in defaults
+func Current() (*Config, error) {
+	cfg := Defaults()
+
+	settings := map[string]any{}
+	for _, k := range Keys() {
+		if viper.IsSet(k.Name) {
+			// Read each key on its own: viper splits nested keys on dots,
+			// which model names such as llama3.1 contain
+			settings[k.Name] = viper.Get(k.Name)
+		}
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName:          "yaml",
+		WeaklyTypedInput: true,
+		ErrorUnused:      true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		Result: &cfg,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(settings); err != nil {
+		return &cfg, fmt.Errorf("invalid configuration: %v", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return &cfg, err
+	}
+	return &cfg, nil
+}
+
+// Validate checks the enumerated values
+func (c *Config) Validate() error {
+	v := reflect.ValueOf(*c)
+	for i, k := range Keys() {
+		if err := k.check(v.Field(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// current returns the effective configuration, falling back to whatever
+// could be decoded when it is invalid. Load reports invalid settings up
+// front.
+func current() *Config {
+	cfg, _ := Current()
+	return cfg
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,131 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Strategy decides how staged changes are presented to the model
+type Strategy string
+
+const (
+	// StrategyAuto picks the best strategy that fits the budget
+	StrategyAuto Strategy = "auto"
+
+	// StrategyFull sends the whole diff
+	StrategyFull Strategy = "full"
+
+	// StrategySummarize summarises each file or hunk separately (map step)
+	// and asks for the message from the summaries (reduce step)
+	StrategySummarize Strategy = "summarize"
+
+	// StrategyStat sends the file list with line counts and the names of the
+	// changed functions only
+	StrategyStat Strategy = "stat"
+)
+
+// Strategies lists the valid strategy names
+var Strategies = []Strategy{StrategyAuto, StrategyFull, StrategySummarize, StrategyStat}
+
+// EstimateTokens approximates the number of model tokens in s. Code and
+// diffs average about four characters per token across common tokenizers.
+func EstimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// Chunk is a piece of a diff small enough to be summarised in one request
+type Chunk struct {
+	Path  string
+	Patch string
+}
+
+// Chunks splits the files into pieces of at most budget tokens. Files that
+// are too large are split by hunk, and hunks that are still too large are
+// truncated.
+func Chunks(files []File, budget int) []Chunk {
+	var chunks []Chunk
+	for _, f := range files {
+		if f.Binary || len(f.Hunks) == 0 {
+			continue
+		}
+
+		whole := f.String()
+		if EstimateTokens(whole) <= budget {
+			chunks = append(chunks, Chunk{Path: f.Path, Patch: whole})
+			continue
+		}
+
+		var current strings.Builder
+		flush := func() {
+			if current.Len() > 0 {
+				chunks = append(chunks, Chunk{Path: f.Path, Patch: f.Header + current.String()})
+				current.Reset()
+			}
+		}
+		for _, h := range f.Hunks {
+			text := h.Header + "\n" + h.Body
+			if EstimateTokens(f.Header+current.String()+text) > budget {
+				flush()
+			}
+			if max := budget*4 - len(f.Header); len(text) > max && max > 0 {
+				text = truncate(text, max) + "\n[... hunk truncated ...]\n"
+			}
+			current.WriteString(text)
+		}
+		flush()
+	}
+	return chunks
+}
+
+// truncate cuts text to at most max bytes, at the end of the last whole
+// line when there is one, and never inside a UTF-8 character
+func truncate(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	if i := strings.LastIndexByte(text[:max], '\n'); i > 0 {
+		return text[:i]
+	}
+	for max > 0 && !utf8.RuneStart(text[max]) {
+		max--
+	}
+	return text[:max]
+}
+
+// Outline describes the changes without their content: a --stat style file
+// list and the functions each file's hunks touch
+func Outline(files []File) string {
+	var b strings.Builder
+	added, deleted := 0, 0
+	for _, f := range files {
+		b.WriteString(f.StatLine() + "\n")
+		for _, ctx := range f.Context() {
+			b.WriteString("    in " + ctx + "\n")
+		}
+		added += f.Added
+		deleted += f.Deleted
+	}
+	b.WriteString(fmt.Sprintf("%d file(s) changed, %d insertion(s)(+), %d deletion(s)(-)\n", len(files), added, deleted))
+	return b.String()
+}
+
+// SummaryNote introduces per-file summaries in place of the diff
+const SummaryNote = "The full diff is too large to include, so it has been replaced by summaries of each changed file:"
+
+// OutlineNote introduces the outline in place of the diff
+const OutlineNote = "The full diff is too large to include, so only the list of changed files and the functions they touch is given:"
+
+// OmittedNote tells the model which files changed without their diffs
+// being shown. It returns an empty string when no file was left out.
+func OmittedNote(files []File) string {
+	if len(files) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("These files also changed, but their diffs are left out:\n")
+	for _, f := range files {
+		b.WriteString("- " + f.Describe() + "\n")
+	}
+	return b.String()
+}
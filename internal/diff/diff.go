@@ -0,0 +1,199 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// File is the part of a unified diff that concerns a single path
+type File struct {
+	Path    string
+	OldPath string
+	Status  string
+	Binary  bool
+	Added   int
+	Deleted int
+
+	// Header holds the "diff --git" line and extended headers up to the
+	// first hunk
+	Header string
+	Hunks  []Hunk
+}
+
+// Hunk is a single "@@" section of a file diff
+type Hunk struct {
+	// Header is the "@@ -a,b +c,d @@ context" line
+	Header string
+	Body   string
+}
+
+// Status values of a File
+const (
+	StatusAdded    = "added"
+	StatusDeleted  = "deleted"
+	StatusModified = "modified"
+	StatusRenamed  = "renamed"
+)
+
+// Parse splits the output of git diff into per-file diffs
+func Parse(raw string) []File {
+	var (
+		files []File
+		file  *File
+		hunk  *Hunk
+		head  strings.Builder
+		body  strings.Builder
+	)
+
+	flushHunk := func() {
+		if hunk != nil {
+			hunk.Body = body.String()
+			file.Hunks = append(file.Hunks, *hunk)
+			hunk = nil
+			body.Reset()
+		}
+	}
+	flushFile := func() {
+		if file != nil {
+			flushHunk()
+			if file.Header == "" {
+				file.Header = head.String()
+			}
+			files = append(files, *file)
+			file = nil
+			head.Reset()
+		}
+	}
+
+	for _, line := range strings.SplitAfter(raw, "\n") {
+		if line == "" {
+			continue
+		}
+		trimmed := strings.TrimRight(line, "\n")
+
+		switch {
+		case strings.HasPrefix(trimmed, "diff --git "):
+			flushFile()
+			file = &File{Status: StatusModified}
+			file.OldPath, file.Path = splitGitPaths(strings.TrimPrefix(trimmed, "diff --git "))
+			head.WriteString(line)
+		case file == nil:
+			continue
+		case strings.HasPrefix(trimmed, "@@"):
+			if hunk == nil && file.Header == "" {
+				file.Header = head.String()
+			}
+			flushHunk()
+			hunk = &Hunk{Header: trimmed}
+		case hunk != nil:
+			body.WriteString(line)
+			switch {
+			case strings.HasPrefix(trimmed, "+"):
+				file.Added++
+			case strings.HasPrefix(trimmed, "-"):
+				file.Deleted++
+			}
+		default:
+			head.WriteString(line)
+			parseExtendedHeader(file, trimmed)
+		}
+	}
+	flushFile()
+	return files
+}
+
+func parseExtendedHeader(file *File, line string) {
+	switch {
+	case strings.HasPrefix(line, "new file mode"):
+		file.Status = StatusAdded
+	case strings.HasPrefix(line, "deleted file mode"):
+		file.Status = StatusDeleted
+	case strings.HasPrefix(line, "rename from "):
+		file.Status = StatusRenamed
+		file.OldPath = strings.TrimPrefix(line, "rename from ")
+	case strings.HasPrefix(line, "rename to "):
+		file.Path = strings.TrimPrefix(line, "rename to ")
+	case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+		file.Binary = true
+	case strings.HasPrefix(line, "+++ ") && line != "+++ /dev/null":
+		file.Path = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+	case strings.HasPrefix(line, "--- ") && line != "--- /dev/null":
+		file.OldPath = strings.TrimPrefix(strings.TrimPrefix(line, "--- "), "a/")
+	}
+}
+
+// splitGitPaths splits "a/old b/new" from a diff --git line. Paths with
+// spaces are ambiguous there; the ---/+++ headers refine them later.
+func splitGitPaths(s string) (string, string) {
+	if i := strings.Index(s, " b/"); i >= 0 {
+		return strings.TrimPrefix(s[:i], "a/"), s[i+3:]
+	}
+	return s, s
+}
+
+// String reassembles the file diff
+func (f File) String() string {
+	var b strings.Builder
+	b.WriteString(f.Header)
+	for _, h := range f.Hunks {
+		b.WriteString(h.Header + "\n")
+		b.WriteString(h.Body)
+	}
+	return b.String()
+}
+
+// Join reassembles a list of file diffs into a single diff
+func Join(files []File) string {
+	var b strings.Builder
+	for _, f := range files {
+		b.WriteString(f.String())
+	}
+	return b.String()
+}
+
+// StatLine describes the file like a line of git diff --stat
+func (f File) StatLine() string {
+	path := f.Path
+	if f.Status == StatusRenamed && f.OldPath != "" && f.OldPath != f.Path {
+		path = f.OldPath + " => " + f.Path
+	}
+	if f.Binary {
+		return fmt.Sprintf("%s | binary (%s)", path, f.Status)
+	}
+	return fmt.Sprintf("%s | +%d -%d (%s)", path, f.Added, f.Deleted, f.Status)
+}
+
+// Describe says what happened to the file without showing its content,
+// e.g. "go.sum updated"
+func (f File) Describe() string {
+	switch f.Status {
+	case StatusAdded:
+		return f.Path + " added"
+	case StatusDeleted:
+		return f.Path + " deleted"
+	case StatusRenamed:
+		return f.OldPath + " renamed to " + f.Path
+	}
+	return f.Path + " updated"
+}
+
+// Context returns the function or section names git printed after the hunk
+// headers, without duplicates
+func (f File) Context() []string {
+	var (
+		out  []string
+		seen = map[string]bool{}
+	)
+	for _, h := range f.Hunks {
+		parts := strings.SplitN(h.Header, "@@", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		ctx := strings.TrimSpace(parts[2])
+		if ctx != "" && !seen[ctx] {
+			seen[ctx] = true
+			out = append(out, ctx)
+		}
+	}
+	return out
+}
@@ -0,0 +1,194 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const sample = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@ package main
+ import "fmt"
++import "os"
+@@ -10,2 +11,2 @@ func main() {
+-	fmt.Println("hi")
++	fmt.Println(os.Args)
+diff --git a/docs/new.md b/docs/new.md
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/docs/new.md
+@@ -0,0 +1 @@
++# New
+diff --git a/logo.png b/logo.png
+index 4444444..5555555 100644
+Binary files a/logo.png and b/logo.png differ
+diff --git a/old.go b/renamed.go
+similarity index 100%
+rename from old.go
+rename to renamed.go
+`
+
+func TestParse(t *testing.T) {
+	files := Parse(sample)
+
+	tests := []struct {
+		name    string
+		index   int
+		path    string
+		oldPath string
+		status  string
+		binary  bool
+		added   int
+		deleted int
+		hunks   int
+	}{
+		{name: "should parse a modified file with several hunks", index: 0, path: "main.go", oldPath: "main.go", status: StatusModified, added: 2, deleted: 1, hunks: 2},
+		{name: "should parse an added file", index: 1, path: "docs/new.md", oldPath: "docs/new.md", status: StatusAdded, added: 1, hunks: 1},
+		{name: "should parse a binary file", index: 2, path: "logo.png", oldPath: "logo.png", status: StatusModified, binary: true},
+		{name: "should parse a pure rename", index: 3, path: "renamed.go", oldPath: "old.go", status: StatusRenamed},
+	}
+
+	if len(files) != len(tests) {
+		t.Fatalf("Parse() returned %d files, want %d", len(files), len(tests))
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := files[tt.index]
+			if f.Path != tt.path || f.OldPath != tt.oldPath {
+				t.Errorf("paths = %q, %q, want %q, %q", f.OldPath, f.Path, tt.oldPath, tt.path)
+			}
+			if f.Status != tt.status || f.Binary != tt.binary {
+				t.Errorf("status = %s (binary %v), want %s (binary %v)", f.Status, f.Binary, tt.status, tt.binary)
+			}
+			if f.Added != tt.added || f.Deleted != tt.deleted {
+				t.Errorf("counts = +%d -%d, want +%d -%d", f.Added, f.Deleted, tt.added, tt.deleted)
+			}
+			if len(f.Hunks) != tt.hunks {
+				t.Errorf("hunks = %d, want %d", len(f.Hunks), tt.hunks)
+			}
+		})
+	}
+
+	if got := Join(files); got != sample {
+		t.Errorf("Join(Parse()) = %q, want the original diff", got)
+	}
+}
+
+func TestChunks(t *testing.T) {
+	files := Parse(sample)
+
+	tests := []struct {
+		name   string
+		budget int
+		want   []string
+	}{
+		{
+			name:   "should keep whole files that fit the budget",
+			budget: 1000,
+			want:   []string{"main.go", "docs/new.md"},
+		},
+		{
+			name:   "should split large files by hunk",
+			budget: 40,
+			want:   []string{"main.go", "main.go", "docs/new.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := Chunks(files, tt.budget)
+			var got []string
+			for _, c := range chunks {
+				got = append(got, c.Path)
+				if !strings.HasPrefix(c.Patch, "diff --git ") {
+					t.Errorf("chunk for %s should start with the file header, got %q", c.Path, c.Patch)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Chunks() paths = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutline(t *testing.T) {
+	got := Outline(Parse(sample))
+
+	for _, want := range []string{
+		"main.go | +2 -1 (modified)",
+		"    in package main",
+		"    in func main() {",
+		"docs/new.md | +1 -0 (added)",
+		"logo.png | binary (modified)",
+		"old.go => renamed.go | +0 -0 (renamed)",
+		"4 file(s) changed, 3 insertion(s)(+), 1 deletion(s)(-)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Outline() = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{name: "should count nothing for empty text", text: "", want: 0},
+		{name: "should round up", text: "abcde", want: 2},
+		{name: "should count four characters per token", text: strings.Repeat("a", 400), want: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateTokens(tt.text); got != tt.want {
+				t.Errorf("EstimateTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		max  int
+		want string
+	}{
+		{
+			name: "should keep text that fits",
+			text: "+a\n+b",
+			max:  10,
+			want: "+a\n+b",
+		},
+		{
+			name: "should cut at the end of the last whole line",
+			text: "+first\n+second line",
+			max:  12,
+			want: "+first",
+		},
+		{
+			name: "should not cut a character in half",
+			text: "+héllo",
+			max:  3,
+			want: "+h",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncate(tt.text, tt.max)
+			if got != tt.want {
+				t.Errorf("truncate() = %q, want %q", got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncate() = %q, want valid UTF-8", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+// Package doctor checks that everything cmt needs is in place: a valid
+// configuration, a git repository with staged changes, the commit hook, and
+// a model server that has the model and answers in reasonable time.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dakoctba/cmt/internal/config"
+	"github.com/dakoctba/cmt/internal/git"
+	"github.com/dakoctba/cmt/internal/hook"
+	"github.com/dakoctba/cmt/internal/provider"
+)
+
+// Status is the outcome of a check
+type Status string
+
+// Check outcomes. Skip marks checks that depend on one that failed.
+const (
+	Pass Status = "pass"
+	Warn Status = "warn"
+	Fail Status = "fail"
+	Skip Status = "skip"
+)
+
+const (
+	// checkTimeout bounds the server checks other than the latency one,
+	// which may have to wait for the model to load
+	checkTimeout = 10 * time.Second
+
+	// slowLatency is the round trip above which the latency check warns
+	slowLatency = 10 * time.Second
+
+	// latencyPrompt is the tiny prompt the round trip is measured with
+	latencyPrompt = "Reply with the single word OK."
+)
+
+// Check is the result of one check
+type Check struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+
+	// Hint says how to fix a failed or worrying check
+	Hint string `json:"hint,omitempty"`
+
+	// LatencyMS is the round trip of the latency check, in milliseconds
+	LatencyMS int64 `json:"latency_ms,omitempty"`
+}
+
+// Report is the result of every check
+type Report struct {
+	// OK is false when a check failed; warnings do not count
+	OK     bool    `json:"ok"`
+	Checks []Check `json:"checks"`
+}
+
+// Failed returns the names of the failed checks
+func (r Report) Failed() []string {
+	var names []string
+	for _, c := range r.Checks {
+		if c.Status == Fail {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// Run checks the environment with the loaded configuration. configFile is
+// the config file given on the command line, if any, and loadErr the error
+// loading the configuration; the other checks then run with the defaults.
+func Run(ctx context.Context, configFile string, loadErr error) Report {
+	var r Report
+	add := func(c Check) Check {
+		r.Checks = append(r.Checks, c)
+		return c
+	}
+
+	add(checkConfig(configFile, loadErr))
+
+	repo := add(checkRepo(ctx))
+	if repo.Status == Pass {
+		add(checkStaged(ctx))
+		add(checkHook(ctx))
+	} else {
+		add(skipped("staged changes", "not in a git repository"))
+		add(skipped("hook", "not in a git repository"))
+	}
+
+	llm, server := checkServer(ctx)
+	add(server)
+	if server.Status == Fail {
+		add(skipped("model", "the model server is not reachable"))
+		add(skipped("latency", "the model server is not reachable"))
+	} else if model := add(checkModel(ctx, llm)); model.Status == Fail {
+		add(skipped("latency", "the model is not available"))
+	} else {
+		add(checkLatency(ctx, llm))
+	}
+
+	r.OK = len(r.Failed()) == 0
+	return r
+}
+
+func skipped(name, reason string) Check {
+	return Check{Name: name, Status: Skip, Message: "skipped, " + reason}
+}
+
+func checkConfig(configFile string, loadErr error) Check {
+	c := Check{Name: "config"}
+	if loadErr != nil {
+		c.Status, c.Message = Fail, loadErr.Error()
+		c.Hint = `fix the file, "cmt config validate" lists every problem`
+		return c
+	}
+
+	candidates := []string{configFile}
+	if configFile == "" {
+		candidates, _ = config.GlobalFiles()
+	}
+	var files []string
+	for _, path := range append(candidates, config.RepoFile()) {
+		if _, err := os.Stat(path); path != "" && err == nil {
+			files = append(files, path)
+		}
+	}
+	c.Status, c.Message = Pass, "using the built-in defaults"
+	if len(files) > 0 {
+		c.Message = "loaded " + strings.Join(files, ", ")
+	}
+	return c
+}
+
+func checkRepo(ctx context.Context) Check {
+	c := Check{Name: "repository"}
+	root, err := git.RepoRoot(ctx)
+	if err != nil {
+		c.Status, c.Message = Fail, err.Error()
+		c.Hint = `run cmt inside a git repository, or create one with "git init"`
+		return c
+	}
+	c.Status, c.Message = Pass, root
+	return c
+}
+
+func checkStaged(ctx context.Context) Check {
+	c := Check{Name: "staged changes"}
+	files, err := git.GetStagedFiles(ctx)
+	switch {
+	case err != nil:
+		c.Status, c.Message = Fail, err.Error()
+	case len(files) == 0:
+		c.Status, c.Message = Warn, "nothing is staged"
+		c.Hint = `stage the changes to describe with "git add"`
+	default:
+		c.Status, c.Message = Pass, fmt.Sprintf("%d file(s) staged", len(files))
+	}
+	return c
+}
+
+func checkHook(ctx context.Context) Check {
+	c := Check{Name: "hook"}
+	path, err := hook.Path(ctx)
+	if err != nil {
+		c.Status, c.Message = Fail, err.Error()
+		return c
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		c.Status, c.Message = Pass, `not installed, "cmt hook install" writes messages from "git commit"`
+	case err != nil:
+		c.Status, c.Message = Fail, err.Error()
+	case !hook.Installed(path):
+		c.Status, c.Message = Pass, fmt.Sprintf("%s was not written by cmt", path)
+	case info.Mode()&0111 == 0:
+		c.Status, c.Message = Fail, fmt.Sprintf("%s is not executable, so git skips it", path)
+		c.Hint = `run "cmt hook install" again, or "chmod +x ` + path + `"`
+	default:
+		c.Status, c.Message = Pass, "installed at "+path
+	}
+	return c
+}
+
+func checkServer(ctx context.Context) (provider.Provider, Check) {
+	c := Check{Name: "server"}
+	llm, err := provider.New(config.GetProvider(), config.GetBaseURL(), config.GetAPIKey())
+	if err != nil {
+		c.Status, c.Message = Fail, err.Error()
+		c.Hint = "set provider to one of " + strings.Join(provider.Names, ", ")
+		return nil, c
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	if err := llm.Health(ctx); err != nil {
+		c.Status, c.Message = Fail, err.Error()
+		c.Hint = "check base_url"
+		if llm.Name() == provider.Ollama {
+			c.Hint = `start Ollama with "ollama serve", or point OLLAMA_HOST or base_url at your server`
+		}
+		return llm, c
+	}
+	c.Status, c.Message = Pass, fmt.Sprintf("%s is answering at %s", llm.Name(), llm.BaseURL())
+	if llm.Name() == provider.OpenAI && config.GetAPIKey() == "" {
+		c.Status, c.Message = Warn, c.Message+", without an API key"
+		c.Hint = fmt.Sprintf("export the key as %s if the server needs one", config.GetAPIKeyEnv())
+	}
+	return llm, c
+}
+
+func checkModel(ctx context.Context, llm provider.Provider) Check {
+	c := Check{Name: "model"}
+	model := config.GetModel()
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	models, err := llm.ListModels(ctx)
+	if err != nil {
+		c.Status, c.Message = Warn, fmt.Sprintf("could not list the models: %v", err)
+		return c
+	}
+
+	if _, ok := provider.FindModel(models, model); !ok {
+		c.Status, c.Message = Fail, fmt.Sprintf("%s is not available on the server", model)
+		c.Hint = pullHint(llm, []string{model}, models)
+		return c
+	}
+
+	var missing []string
+	for _, m := range config.GetFallbackModels() {
+		if _, ok := provider.FindModel(models, m); !ok {
+			missing = append(missing, m)
+		}
+	}
+	if len(missing) > 0 {
+		c.Status = Warn
+		c.Message = fmt.Sprintf("%s is available, but not the fallback model(s) %s", model, strings.Join(missing, ", "))
+		c.Hint = pullHint(llm, missing, models)
+		return c
+	}
+	c.Status, c.Message = Pass, model+" is available"
+	return c
+}
+
+// pullHint says how to get missing models: pulling them from Ollama, or
+// picking one the server has
+func pullHint(llm provider.Provider, missing []string, models []provider.Model) string {
+	if llm.Name() == provider.Ollama {
+		return `run "cmt models pull ` + strings.Join(missing, `" and "cmt models pull `) + `"`
+	}
+	names := make([]string, len(models))
+	for i, m := range models {
+		names[i] = m.Name
+	}
+	return "use one of the server's models: " + strings.Join(names, ", ")
+}
+
+func checkLatency(ctx context.Context, llm provider.Provider) Check {
+	c := Check{Name: "latency"}
+
+	// The configured timeout applies, as the model may need loading first
+	if timeout := config.GetTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req := provider.Prompt(config.GetModel(), latencyPrompt)
+	req.Options.NumPredict = 8
+
+	start := time.Now()
+	_, err := llm.Generate(ctx, req)
+	elapsed := time.Since(start)
+	if err != nil {
+		c.Status, c.Message = Fail, fmt.Sprintf("the model did not answer: %v", err)
+		c.Hint = "check the server's logs; slow machines may need a longer timeout"
+		return c
+	}
+
+	c.LatencyMS = elapsed.Milliseconds()
+	c.Status, c.Message = Pass, fmt.Sprintf("answered in %s", elapsed.Round(time.Millisecond))
+	if elapsed > slowLatency {
+		c.Status = Warn
+		c.Hint = "the model may have been loading, run cmt doctor again; a smaller model answers faster"
+	}
+	return c
+}
@@ -0,0 +1,127 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dakoctba/cmt/internal/git/gittest"
+	"github.com/dakoctba/cmt/internal/hook"
+	"github.com/dakoctba/cmt/internal/ollama/ollamatest"
+	"github.com/spf13/viper"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, server *ollamatest.Server)
+		loadErr error
+		want    map[string]Status
+		wantOK  bool
+	}{
+		{
+			name: "should pass when everything is in place",
+			setup: func(t *testing.T, server *ollamatest.Server) {
+				dir := gittest.Chdir(t)
+				gittest.Stage(t, dir, "feature.txt", "new feature")
+			},
+			want: map[string]Status{
+				"config": Pass, "repository": Pass, "staged changes": Pass, "hook": Pass,
+				"server": Pass, "model": Pass, "latency": Pass,
+			},
+			wantOK: true,
+		},
+		{
+			name:    "should report an invalid config",
+			setup:   func(t *testing.T, server *ollamatest.Server) { gittest.Chdir(t) },
+			loadErr: errors.New("config.yaml:1:1: unknown key \"streem\""),
+			want:    map[string]Status{"config": Fail, "staged changes": Warn, "latency": Pass},
+		},
+		{
+			name: "should skip the repository checks outside a repository",
+			setup: func(t *testing.T, server *ollamatest.Server) {
+				wd, _ := os.Getwd()
+				os.Chdir(t.TempDir())
+				t.Cleanup(func() { os.Chdir(wd) })
+			},
+			want: map[string]Status{"repository": Fail, "staged changes": Skip, "hook": Skip, "server": Pass},
+		},
+		{
+			name: "should fail on a hook git cannot run",
+			setup: func(t *testing.T, server *ollamatest.Server) {
+				gittest.Chdir(t)
+				path, err := hook.Install(context.Background(), "/usr/local/bin/cmt")
+				if err != nil {
+					t.Fatalf("Install() error = %v", err)
+				}
+				os.Chmod(path, 0644)
+			},
+			want: map[string]Status{"hook": Fail},
+		},
+		{
+			name: "should skip the latency check when the model is missing",
+			setup: func(t *testing.T, server *ollamatest.Server) {
+				gittest.Chdir(t)
+				server.Models = []string{"phi3"}
+			},
+			want: map[string]Status{"model": Fail, "latency": Skip},
+		},
+		{
+			name: "should warn about missing fallback models",
+			setup: func(t *testing.T, server *ollamatest.Server) {
+				gittest.Chdir(t)
+				viper.Set("fallback_models", []string{"phi3"})
+			},
+			want:   map[string]Status{"model": Warn, "latency": Pass},
+			wantOK: true,
+		},
+		{
+			name: "should skip the model checks when the server is down",
+			setup: func(t *testing.T, server *ollamatest.Server) {
+				gittest.Chdir(t)
+				down := httptest.NewServer(http.NotFoundHandler())
+				down.Close()
+				viper.Set("base_url", down.URL)
+			},
+			want: map[string]Status{"server": Fail, "model": Skip, "latency": Skip},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("XDG_CONFIG_HOME", filepath.Join(t.TempDir(), "config"))
+			server := ollamatest.NewServer(t)
+			server.Models = []string{"llama3.1:latest"}
+
+			viper.Reset()
+			viper.Set("model", "llama3.1")
+			defer viper.Reset()
+			tt.setup(t, server)
+
+			report := Run(context.Background(), "", tt.loadErr)
+			got := map[string]Status{}
+			for _, c := range report.Checks {
+				got[c.Name] = c.Status
+				if c.Status == Fail && c.Hint == "" {
+					t.Errorf("check %s failed without a hint: %s", c.Name, c.Message)
+				}
+			}
+			if len(report.Checks) != 7 {
+				t.Errorf("Run() made %d checks, want 7", len(report.Checks))
+			}
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("check %s = %s, want %s (%+v)", name, got[name], want, report.Checks)
+				}
+			}
+			if report.OK != tt.wantOK {
+				t.Errorf("Run().OK = %v, want %v", report.OK, tt.wantOK)
+			}
+		})
+	}
+}
@@ -1,25 +1,283 @@
 package git
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dakoctba/cmt/internal/diff"
+	"github.com/dakoctba/cmt/internal/ui"
 )
 
 // CheckRepo verifies if the current directory is a Git repository
-func CheckRepo() error {
-	cmd := exec.Command("git", "rev-parse", "--is-inside-work-tree")
+func CheckRepo(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--is-inside-work-tree")
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("this is not a Git repository. Please run this command inside a Git repository")
 	}
 	return nil
 }
 
+// RepoRoot returns the top-level directory of the current repository
+func RepoRoot(ctx context.Context) (string, error) {
+	output, err := exec.CommandContext(ctx, "git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find repository root: %v", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// CurrentBranch returns the name of the checked-out branch. While a rebase
+// is in progress HEAD is detached, and the branch being rebased is returned
+// instead; otherwise a detached HEAD gives an empty string.
+func CurrentBranch(ctx context.Context) (string, error) {
+	output, err := exec.CommandContext(ctx, "git", "symbolic-ref", "--quiet", "--short", "HEAD").Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return rebasedBranch(ctx), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the current branch: %v", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// rebasedBranch returns the branch of an ongoing rebase, if any
+func rebasedBranch(ctx context.Context) string {
+	for _, name := range []string{"rebase-merge/head-name", "rebase-apply/head-name"} {
+		path, err := Path(ctx, name)
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		// A detached HEAD being rebased is recorded as "detached HEAD"
+		if name, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "refs/heads/"); ok {
+			return name
+		}
+		return ""
+	}
+	return ""
+}
+
+// HasCommits reports whether HEAD points to a commit, which it does not
+// before the first commit of a repository
+func HasCommits(ctx context.Context) bool {
+	return exec.CommandContext(ctx, "git", "rev-parse", "--quiet", "--verify", "HEAD").Run() == nil
+}
+
+// HooksDir returns the absolute path of the directory git runs hooks from,
+// honouring core.hooksPath
+func HooksDir(ctx context.Context) (string, error) {
+	path, err := Path(ctx, "hooks")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(path)
+}
+
 // GetStagedDiff returns the staged changes as a string
-func GetStagedDiff() (string, error) {
-	cmd := exec.Command("git", "diff", "--cached")
+func GetStagedDiff(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "diff", "--cached", "--no-ext-diff")
 	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get staged diff: %v", err)
 	}
 	return string(output), nil
 }
+
+// GetStagedFiles returns the staged changes split into per-file diffs
+func GetStagedFiles(ctx context.Context) ([]diff.File, error) {
+	raw, err := GetStagedDiff(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return diff.Parse(raw), nil
+}
+
+// ListFiles returns the paths of the files in the index matching the
+// pathspecs, relative to the repository root
+func ListFiles(ctx context.Context, pathspecs ...string) ([]string, error) {
+	args := append([]string{"ls-files", "-z", "--full-name", "--"}, pathspecs...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	if root, err := RepoRoot(ctx); err == nil {
+		cmd.Dir = root
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %v", err)
+	}
+
+	var files []string
+	for _, f := range strings.Split(string(output), "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// CommitOptions holds the git commit flags passed through by cmt
+type CommitOptions struct {
+	// Signoff adds a Signed-off-by trailer (--signoff)
+	Signoff bool
+
+	// Sign GPG-signs the commit (-S), using SignKey when it is not empty
+	Sign    bool
+	SignKey string
+
+	// NoVerify bypasses the pre-commit and commit-msg hooks (--no-verify)
+	NoVerify bool
+}
+
+// Args returns the git commit arguments for the options
+func (o CommitOptions) Args() []string {
+	var args []string
+	if o.Signoff {
+		args = append(args, "--signoff")
+	}
+	if o.Sign {
+		args = append(args, "-S"+o.SignKey)
+	}
+	if o.NoVerify {
+		args = append(args, "--no-verify")
+	}
+	return args
+}
+
+// Commit creates a commit from the staged changes with the given message.
+// Git's own output (including hook output) goes to stderr, keeping stdout
+// for cmt's output.
+func Commit(ctx context.Context, message string, opts CommitOptions) error {
+	args := append([]string{"commit", "-F", "-"}, opts.Args()...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stdin = strings.NewReader(message)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git commit failed: %v", err)
+	}
+	return nil
+}
+
+// Editor returns the editor git would use, honouring GIT_EDITOR,
+// core.editor, VISUAL and EDITOR in that order
+func Editor(ctx context.Context) (string, error) {
+	output, err := exec.CommandContext(ctx, "git", "var", "GIT_EDITOR").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine editor: %v", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Path resolves a path inside the repository's git directory
+func Path(ctx context.Context, name string) (string, error) {
+	output, err := exec.CommandContext(ctx, "git", "rev-parse", "--git-path", name).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve git path %s: %v", name, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// EditFile opens the user's editor, as configured for git, on path and
+// waits for it to exit. ctx only bounds looking the editor up: the editor
+// is not killed when ctx is cancelled, and Ctrl-C is left to it while it
+// runs.
+func EditFile(ctx context.Context, path string) error {
+	editor, err := Editor(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Run the editor through the shell like git does, so editor settings
+	// with arguments (e.g. "code --wait") work
+	cmd := exec.Command("sh", "-c", editor+` "$@"`, editor, path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	restore := ui.IgnoreInterrupts()
+	defer restore()
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %v", editor, err)
+	}
+	return nil
+}
+
+// EditMessage opens the user's editor on text and returns the edited
+// message with comment lines and surrounding whitespace removed
+func EditMessage(ctx context.Context, text string) (string, error) {
+	path, err := Path(ctx, "CMT_EDITMSG")
+	if err != nil {
+		return "", err
+	}
+
+	content := text + "\n\n" + editHint
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write message file: %v", err)
+	}
+	defer os.Remove(path)
+
+	if err := EditFile(ctx, path); err != nil {
+		return "", err
+	}
+
+	edited, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read message file: %v", err)
+	}
+	return StripComments(string(edited)), nil
+}
+
+const editHint = `# Edit the commit message above. Lines starting with '#' are ignored,
+# and an empty message aborts the commit.`
+
+// StripComments removes '#' comment lines and surrounding blank lines the
+// way git's default cleanup mode does
+func StripComments(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t\r"))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// LoggedCommit is a commit read from the history
+type LoggedCommit struct {
+	Hash    string
+	Message string
+}
+
+// Log returns the commits in revRange (e.g. "main..HEAD"), newest first.
+// A limit of zero or less returns every commit in the range.
+func Log(ctx context.Context, revRange string, limit int) ([]LoggedCommit, error) {
+	args := []string{"log", "--format=%H%x00%B%x1e"}
+	if limit > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", limit))
+	}
+	if revRange != "" {
+		args = append(args, revRange)
+	}
+	args = append(args, "--")
+
+	output, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit history: %v", err)
+	}
+
+	var commits []LoggedCommit
+	for _, record := range strings.Split(string(output), "\x1e") {
+		hash, msg, ok := strings.Cut(strings.TrimLeft(record, "\n"), "\x00")
+		if !ok {
+			continue
+		}
+		commits = append(commits, LoggedCommit{Hash: hash, Message: strings.TrimSpace(msg)})
+	}
+	return commits, nil
+}
@@ -0,0 +1,59 @@
+// Package gittest provides throwaway git repositories for tests.
+package gittest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// Chdir creates an empty git repository in a temporary directory, makes it
+// the working directory for the rest of the test and returns its path
+func Chdir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	Run(t, dir, "init", "-q")
+	Run(t, dir, "config", "user.name", "cmt test")
+	Run(t, dir, "config", "user.email", "cmt@example.com")
+	Run(t, dir, "config", "commit.gpgsign", "false")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	return dir
+}
+
+// Stage writes a file relative to dir and adds it to the index
+func Stage(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	Run(t, dir, "add", name)
+}
+
+// Run executes a git command in dir and returns its output
+func Run(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
@@ -0,0 +1,138 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dakoctba/cmt/internal/git"
+	"github.com/dakoctba/cmt/internal/message"
+)
+
+// Name is the git hook cmt installs
+const Name = "prepare-commit-msg"
+
+// chainedSuffix is appended to a pre-existing hook that cmt chains to
+const chainedSuffix = ".pre-cmt"
+
+// marker identifies hook scripts written by cmt
+const marker = "# Installed by cmt: generates the commit message from the staged changes."
+
+// Path returns the location of the prepare-commit-msg hook, honouring
+// core.hooksPath
+func Path(ctx context.Context) (string, error) {
+	dir, err := git.HooksDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, Name), nil
+}
+
+// Installed reports whether the cmt hook is installed at path
+func Installed(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && bytes.Contains(data, []byte(marker))
+}
+
+// Install writes the prepare-commit-msg hook. An existing hook that was not
+// written by cmt is kept and run before cmt. It returns the hook path.
+func Install(ctx context.Context, executable string) (string, error) {
+	path, err := Path(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create hooks directory: %v", err)
+	}
+
+	if _, err := os.Stat(path); err == nil && !Installed(path) {
+		chained := path + chainedSuffix
+		if _, err := os.Stat(chained); err == nil {
+			return "", fmt.Errorf("both %s and %s exist; move one of them out of the way first", path, chained)
+		}
+		if err := os.Rename(path, chained); err != nil {
+			return "", fmt.Errorf("failed to keep existing hook: %v", err)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(Script(executable)), 0755); err != nil {
+		return "", fmt.Errorf("failed to write hook: %v", err)
+	}
+	return path, nil
+}
+
+// Uninstall removes the cmt hook and restores the hook it was chained to.
+// It returns the hook path.
+func Uninstall(ctx context.Context) (string, error) {
+	path, err := Path(ctx)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", fmt.Errorf("no %s hook is installed", Name)
+	}
+	if !Installed(path) {
+		return "", fmt.Errorf("%s was not installed by cmt; leaving it alone", path)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return "", fmt.Errorf("failed to remove hook: %v", err)
+	}
+	chained := path + chainedSuffix
+	if _, err := os.Stat(chained); err == nil {
+		if err := os.Rename(chained, path); err != nil {
+			return "", fmt.Errorf("failed to restore previous hook: %v", err)
+		}
+	}
+	return path, nil
+}
+
+// Script returns the hook script. It runs any chained hook first, then cmt,
+// and never blocks the commit when cmt is missing or fails.
+func Script(executable string) string {
+	return fmt.Sprintf(`#!/bin/sh
+%s
+# Remove it with "cmt hook uninstall".
+
+previous="$0%s"
+if [ -x "$previous" ]; then
+	"$previous" "$@" || exit $?
+fi
+
+cmt=%s
+if [ ! -x "$cmt" ]; then
+	cmt=cmt
+fi
+command -v "$cmt" >/dev/null 2>&1 || exit 0
+
+"$cmt" hook run "$@" || true
+`, marker, chainedSuffix, message.ShellQuote(executable))
+}
+
+// ShouldRun reports whether a message should be generated for the commit
+// source git passes to prepare-commit-msg. Messages given with -m/-F, merges,
+// squashes and amends or -c/-C commits already have a message.
+func ShouldRun(source string) bool {
+	switch source {
+	case "", "template":
+		return true
+	}
+	return false
+}
+
+// CommentOut prefixes every line of text with the comment character so git
+// strips it from the final message
+func CommentOut(text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if line == "" {
+			b.WriteString("#\n")
+			continue
+		}
+		b.WriteString("# " + line + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,115 @@
+package hook
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dakoctba/cmt/internal/git/gittest"
+)
+
+func TestInstallUninstall(t *testing.T) {
+	tests := []struct {
+		name      string
+		hooksPath string
+		existing  string
+	}{
+		{
+			name: "should install into .git/hooks",
+		},
+		{
+			name:      "should honour core.hooksPath",
+			hooksPath: ".githooks",
+		},
+		{
+			name:     "should chain and restore an existing hook",
+			existing: "#!/bin/sh\necho previous\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := gittest.Chdir(t)
+			if tt.hooksPath != "" {
+				gittest.Run(t, dir, "config", "core.hooksPath", tt.hooksPath)
+			}
+
+			want, err := Path(context.Background())
+			if err != nil {
+				t.Fatalf("Path() error = %v", err)
+			}
+			if tt.hooksPath != "" && !strings.HasPrefix(want, filepath.Join(dir, tt.hooksPath)) {
+				t.Errorf("Path() = %v, want inside %v", want, tt.hooksPath)
+			}
+			if tt.existing != "" {
+				os.MkdirAll(filepath.Dir(want), 0755)
+				os.WriteFile(want, []byte(tt.existing), 0755)
+			}
+
+			path, err := Install(context.Background(), "/usr/local/bin/cmt")
+			if err != nil {
+				t.Fatalf("Install() error = %v", err)
+			}
+			if !Installed(path) {
+				t.Errorf("Install() did not write the cmt hook to %v", path)
+			}
+			if tt.existing != "" {
+				chained, _ := os.ReadFile(path + chainedSuffix)
+				if string(chained) != tt.existing {
+					t.Errorf("existing hook = %q, want %q", chained, tt.existing)
+				}
+			}
+
+			// Installing twice must not chain the cmt hook to itself
+			if _, err := Install(context.Background(), "/usr/local/bin/cmt"); err != nil {
+				t.Fatalf("second Install() error = %v", err)
+			}
+
+			if _, err := Uninstall(context.Background()); err != nil {
+				t.Fatalf("Uninstall() error = %v", err)
+			}
+			restored, err := os.ReadFile(path)
+			switch {
+			case tt.existing == "" && !os.IsNotExist(err):
+				t.Errorf("Uninstall() left %v behind", path)
+			case tt.existing != "" && string(restored) != tt.existing:
+				t.Errorf("restored hook = %q, want %q", restored, tt.existing)
+			}
+		})
+	}
+}
+
+func TestUninstallForeignHook(t *testing.T) {
+	gittest.Chdir(t)
+	path, _ := Path(context.Background())
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, []byte("#!/bin/sh\n"), 0755)
+
+	if _, err := Uninstall(context.Background()); err == nil {
+		t.Error("Uninstall() should refuse to remove a hook cmt did not install")
+	}
+}
+
+func TestShouldRun(t *testing.T) {
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{source: "", want: true},
+		{source: "template", want: true},
+		{source: "message", want: false},
+		{source: "merge", want: false},
+		{source: "squash", want: false},
+		{source: "commit", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run("source "+tt.source, func(t *testing.T) {
+			if got := ShouldRun(tt.source); got != tt.want {
+				t.Errorf("ShouldRun(%q) = %v, want %v", tt.source, got, tt.want)
+			}
+		})
+	}
+}
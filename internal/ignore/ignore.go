@@ -0,0 +1,242 @@
+// Package ignore decides which changed files are left out of the prompt,
+// using gitignore syntax.
+package ignore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileName is the per-repository ignore file, read from the repository root
+const FileName = ".cmtignore"
+
+// Defaults are the patterns applied before the configured ones: lockfiles,
+// vendored dependencies and generated code. They can be re-included with a
+// negated pattern, e.g. "!go.sum".
+var Defaults = []string{
+	// Lockfiles
+	"go.sum",
+	"package-lock.json",
+	"npm-shrinkwrap.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+	"bun.lockb",
+	"Cargo.lock",
+	"Gemfile.lock",
+	"composer.lock",
+	"poetry.lock",
+	"Pipfile.lock",
+	"uv.lock",
+
+	// Vendored dependencies
+	"vendor/",
+	"node_modules/",
+
+	// Generated code
+	"*.pb.go",
+	"*.pb.gw.go",
+	"*_pb2.py",
+	"*_pb2_grpc.py",
+	"*.pb.h",
+	"*.pb.cc",
+	"*_generated.go",
+	"*.min.js",
+	"*.min.css",
+	"*.map",
+}
+
+// Matcher reports whether a path is excluded. Patterns are applied in
+// order and the last one matching a path wins, as in .gitignore.
+type Matcher struct {
+	rules []rule
+}
+
+type rule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+	// base patterns have no slash and match any path component
+	base bool
+	re   *regexp.Regexp
+}
+
+// New compiles the given gitignore-style patterns. Blank lines and lines
+// starting with # are skipped.
+func New(patterns []string) (*Matcher, error) {
+	m := &Matcher{}
+	if err := m.Add(patterns...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Load builds the matcher for the repository at root: the built-in defaults
+// (unless useDefaults is false), then extra patterns from the configuration,
+// then the repository's .cmtignore if it exists
+func Load(root string, extra []string, useDefaults bool) (*Matcher, error) {
+	m := &Matcher{}
+	if useDefaults {
+		if err := m.Add(Defaults...); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.Add(extra...); err != nil {
+		return nil, fmt.Errorf("invalid ignore pattern in config: %w", err)
+	}
+
+	path := filepath.Join(root, FileName)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	defer f.Close()
+
+	patterns, err := read(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if err := m.Add(patterns...); err != nil {
+		return nil, fmt.Errorf("invalid pattern in %s: %w", path, err)
+	}
+	return m, nil
+}
+
+func read(r io.Reader) ([]string, error) {
+	var patterns []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+	return patterns, scanner.Err()
+}
+
+// Add appends patterns to the matcher
+func (m *Matcher) Add(patterns ...string) error {
+	for _, p := range patterns {
+		r, ok, err := compile(p)
+		if err != nil {
+			return err
+		}
+		if ok {
+			m.rules = append(m.rules, r)
+		}
+	}
+	return nil
+}
+
+// Match reports whether the slash-separated path, relative to the
+// repository root, is excluded
+func (m *Matcher) Match(path string) bool {
+	path = strings.TrimPrefix(filepath.ToSlash(path), "/")
+	parts := strings.Split(path, "/")
+
+	excluded := false
+	for _, r := range m.rules {
+		if r.matches(parts) {
+			excluded = !r.negate
+		}
+	}
+	return excluded
+}
+
+// matches tries the rule against the path and each of its parent
+// directories, so that excluding a directory excludes everything below it
+func (r rule) matches(parts []string) bool {
+	for i := range parts {
+		isDir := i < len(parts)-1
+		if r.dirOnly && !isDir {
+			continue
+		}
+		subject := strings.Join(parts[:i+1], "/")
+		if r.base {
+			subject = parts[i]
+		}
+		if r.re.MatchString(subject) {
+			return true
+		}
+	}
+	return false
+}
+
+func compile(pattern string) (rule, bool, error) {
+	line := strings.TrimRight(pattern, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false, nil
+	}
+
+	r := rule{pattern: line}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule{}, false, nil
+	}
+
+	r.base = !strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	re, err := regexp.Compile("^" + translate(line) + "$")
+	if err != nil {
+		return rule{}, false, fmt.Errorf("%q: %v", pattern, err)
+	}
+	r.re = re
+	return r, true, nil
+}
+
+// translate turns a glob into a regular expression. * and ? do not match
+// slashes, ** matches across directories.
+func translate(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
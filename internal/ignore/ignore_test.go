@@ -0,0 +1,78 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		want     bool
+	}{
+		{name: "should match a file name at any depth", patterns: []string{"go.sum"}, path: "tools/go.sum", want: true},
+		{name: "should match wildcards against the base name", patterns: []string{"*.pb.go"}, path: "api/v1/user.pb.go", want: true},
+		{name: "should not let wildcards cross directories", patterns: []string{"api/*.go"}, path: "api/v1/user.go", want: false},
+		{name: "should match everything below an excluded directory", patterns: []string{"vendor/"}, path: "vendor/github.com/x/y.go", want: true},
+		{name: "should not match files named like a directory pattern", patterns: []string{"vendor/"}, path: "docs/vendor", want: false},
+		{name: "should anchor patterns with a leading slash", patterns: []string{"/build"}, path: "cmd/build/main.go", want: false},
+		{name: "should anchor patterns containing a slash", patterns: []string{"gen/api"}, path: "gen/api/types.go", want: true},
+		{name: "should match across directories with **", patterns: []string{"docs/**/*.png"}, path: "docs/a/b/logo.png", want: true},
+		{name: "should match a leading ** at the root", patterns: []string{"**/testdata"}, path: "testdata/x.json", want: true},
+		{name: "should re-include negated paths", patterns: []string{"*.lock", "!Cargo.lock"}, path: "Cargo.lock", want: false},
+		{name: "should let the last matching pattern win", patterns: []string{"!go.sum", "go.sum"}, path: "go.sum", want: true},
+		{name: "should support character classes", patterns: []string{"file[0-9].txt"}, path: "file7.txt", want: true},
+		{name: "should skip comments and blank lines", patterns: []string{"# go.sum", ""}, path: "go.sum", want: false},
+		{name: "should keep unmatched paths", patterns: Defaults, path: "main.go", want: false},
+		{name: "should exclude lockfiles by default", patterns: Defaults, path: "web/package-lock.json", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(tt.patterns)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := m.Match(tt.path); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	content := "# generated\ndocs/api.md\n!go.sum\n"
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", FileName, err)
+	}
+
+	tests := []struct {
+		name        string
+		extra       []string
+		useDefaults bool
+		path        string
+		want        bool
+	}{
+		{name: "should apply .cmtignore patterns", useDefaults: true, path: "docs/api.md", want: true},
+		{name: "should let .cmtignore re-include defaults", useDefaults: true, path: "go.sum", want: false},
+		{name: "should apply defaults", useDefaults: true, path: "yarn.lock", want: true},
+		{name: "should skip defaults when disabled", path: "yarn.lock", want: false},
+		{name: "should apply patterns from the config", extra: []string{"*.snap"}, path: "ui/__snapshots__/a.snap", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Load(dir, tt.extra, tt.useDefaults)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if got := m.Match(tt.path); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
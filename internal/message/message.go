@@ -0,0 +1,126 @@
+package message
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Format selects how a message is rendered
+type Format string
+
+const (
+	// FormatText renders the header, body and footers separated by blank lines
+	FormatText Format = "text"
+
+	// FormatShell renders a safely quoted git commit command
+	FormatShell Format = "shell"
+
+	// FormatJSON renders the structured fields as JSON
+	FormatJSON Format = "json"
+)
+
+// Footer is a git trailer such as "Refs: PROJ-1" or "BREAKING CHANGE: ..."
+type Footer struct {
+	Token string `json:"token"`
+	Value string `json:"value"`
+}
+
+// Message is a commit message following the Conventional Commits structure
+type Message struct {
+	Type     string   `json:"type"`
+	Scope    string   `json:"scope,omitempty"`
+	Breaking bool     `json:"breaking"`
+	Subject  string   `json:"subject"`
+	Body     string   `json:"body,omitempty"`
+	Footers  []Footer `json:"footers,omitempty"`
+}
+
+// Header returns the first line of the message, e.g. "feat(api)!: add login"
+func (m *Message) Header() string {
+	if m.Type == "" {
+		return m.Subject
+	}
+
+	var b strings.Builder
+	b.WriteString(m.Type)
+	if m.Scope != "" {
+		b.WriteString("(" + m.Scope + ")")
+	}
+	if m.Breaking {
+		b.WriteString("!")
+	}
+	b.WriteString(": ")
+	b.WriteString(m.Subject)
+	return b.String()
+}
+
+// FooterText returns the footers as trailer lines
+func (m *Message) FooterText() string {
+	lines := make([]string, 0, len(m.Footers))
+	for _, f := range m.Footers {
+		sep := ": "
+		if strings.HasPrefix(f.Value, "#") {
+			sep = " "
+		}
+		lines = append(lines, f.Token+sep+f.Value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Paragraphs returns the header, body and footer blocks that are present
+func (m *Message) Paragraphs() []string {
+	parts := []string{m.Header()}
+	if body := strings.TrimSpace(m.Body); body != "" {
+		parts = append(parts, body)
+	}
+	if footers := m.FooterText(); footers != "" {
+		parts = append(parts, footers)
+	}
+	return parts
+}
+
+// String renders the message as it would be stored by git
+func (m *Message) String() string {
+	return strings.Join(m.Paragraphs(), "\n\n")
+}
+
+// Footer returns the value of the first footer with the given token
+func (m *Message) Footer(token string) (string, bool) {
+	for _, f := range m.Footers {
+		if strings.EqualFold(f.Token, token) {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// Render renders the message in the requested format
+func (m *Message) Render(format Format) (string, error) {
+	switch format {
+	case FormatText, "":
+		return m.String(), nil
+	case FormatShell:
+		args := []string{"git", "commit"}
+		for _, p := range m.Paragraphs() {
+			args = append(args, "-m", ShellQuote(p))
+		}
+		return strings.Join(args, " "), nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(struct {
+			*Message
+			Header string `json:"header"`
+			Text   string `json:"message"`
+		}{m, m.Header(), m.String()}, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to encode message: %v", err)
+		}
+		return string(data), nil
+	}
+	return "", fmt.Errorf("unknown message format %q", format)
+}
+
+// ShellQuote quotes s for POSIX shells using single quotes
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
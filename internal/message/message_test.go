@@ -0,0 +1,159 @@
+package message
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    Message
+		wantErr bool
+	}{
+		{
+			name: "should parse git commit command",
+			raw:  `git commit -m "feat(api): add login endpoint" -m "Adds POST /login backed by the session store."`,
+			want: Message{Type: "feat", Scope: "api", Subject: "add login endpoint", Body: "Adds POST /login backed by the session store."},
+		},
+		{
+			name: "should parse command inside a fenced block with prose around it",
+			raw:  "Here is your commit:\n\n```bash\ngit commit -m 'fix: handle empty diff' -m 'Return early when nothing is staged.'\n```\n\nLet me know if you need changes.",
+			want: Message{Type: "fix", Subject: "handle empty diff", Body: "Return early when nothing is staged."},
+		},
+		{
+			name: "should tolerate broken quoting",
+			raw:  `git commit -m "docs: explain "config" keys" -m "Line one\nLine two`,
+			want: Message{Type: "docs", Subject: "explain config keys", Body: "Line one\nLine two"},
+		},
+		{
+			name: "should read footers and breaking marker from command",
+			raw: `git commit -m "refactor(core)!: drop v1 API" -m "Old handlers are gone." -m "BREAKING CHANGE: v1 clients must upgrade
+Refs: PROJ-12"`,
+			want: Message{
+				Type: "refactor", Scope: "core", Breaking: true, Subject: "drop v1 API", Body: "Old handlers are gone.",
+				Footers: []Footer{{Token: "BREAKING CHANGE", Value: "v1 clients must upgrade"}, {Token: "Refs", Value: "PROJ-12"}},
+			},
+		},
+		{
+			name: "should parse JSON",
+			raw:  "```json\n{\"type\": \"perf\", \"scope\": \"git\", \"subject\": \"cache staged diff\", \"body\": \"Avoids a second git call.\", \"footers\": [\"Closes #42\"]}\n```",
+			want: Message{Type: "perf", Scope: "git", Subject: "cache staged diff", Body: "Avoids a second git call.", Footers: []Footer{{Token: "Closes", Value: "#42"}}},
+		},
+		{
+			name: "should keep the order of JSON footers given as an object",
+			raw:  `{"type": "fix", "subject": "reject empty names", "footers": {"Refs": "PROJ-13", "Closes": "#9", "Acked-by": "Sam", "Co-authored-by": "Kim", "BREAKING CHANGE": "names are required"}}`,
+			want: Message{
+				Type: "fix", Breaking: true, Subject: "reject empty names",
+				Footers: []Footer{{Token: "Refs", Value: "PROJ-13"}, {Token: "Closes", Value: "#9"}, {Token: "Acked-by", Value: "Sam"}, {Token: "Co-authored-by", Value: "Kim"}, {Token: "BREAKING CHANGE", Value: "names are required"}},
+			},
+		},
+		{
+			name: "should parse JSON with title and description",
+			raw:  `{"title": "chore: bump deps", "description": "Routine update."}`,
+			want: Message{Type: "chore", Subject: "bump deps", Body: "Routine update."},
+		},
+		{
+			name: "should parse plain text after an introduction",
+			raw:  "Sure! Here's a commit message:\n\n**feat: support OLLAMA_HOST**\n\nLets the client talk to remote servers.\n\nRefs: #7",
+			want: Message{Type: "feat", Subject: "support OLLAMA_HOST", Body: "Lets the client talk to remote servers.", Footers: []Footer{{Token: "Refs", Value: "#7"}}},
+		},
+		{
+			name: "should keep non-conventional text as subject",
+			raw:  "Update README",
+			want: Message{Subject: "Update README"},
+		},
+		{
+			name:    "should reject empty output",
+			raw:     "  \n```\n```",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse() = %#v, want %#v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	m := &Message{
+		Type:    "fix",
+		Scope:   "ui",
+		Subject: "don't crash on resize",
+		Body:    "Guard against a nil window.",
+		Footers: []Footer{{Token: "Closes", Value: "#3"}},
+	}
+
+	tests := []struct {
+		name   string
+		format Format
+		want   string
+	}{
+		{
+			name:   "should render text",
+			format: FormatText,
+			want:   "fix(ui): don't crash on resize\n\nGuard against a nil window.\n\nCloses #3",
+		},
+		{
+			name:   "should render quoted shell command",
+			format: FormatShell,
+			want:   `git commit -m 'fix(ui): don'\''t crash on resize' -m 'Guard against a nil window.' -m 'Closes #3'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.Render(tt.format)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("should round-trip rendered shell command", func(t *testing.T) {
+		shell, _ := m.Render(FormatShell)
+		got, err := Parse(shell)
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, m) {
+			t.Errorf("Parse(Render()) = %#v, want %#v", got, m)
+		}
+	})
+
+	t.Run("should render JSON", func(t *testing.T) {
+		out, err := m.Render(FormatJSON)
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		var decoded map[string]any
+		if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+			t.Fatalf("Render() produced invalid JSON: %v", err)
+		}
+		if decoded["header"] != "fix(ui): don't crash on resize" || decoded["type"] != "fix" {
+			t.Errorf("Render() JSON = %s", out)
+		}
+	})
+
+	t.Run("should reject unknown format", func(t *testing.T) {
+		if _, err := m.Render("yaml"); err == nil {
+			t.Error("Render() should fail for unknown format")
+		}
+	})
+}
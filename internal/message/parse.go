@@ -0,0 +1,441 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrEmpty is returned when the model output contains no usable message
+var ErrEmpty = errors.New("no commit message found in model output")
+
+// CommonTypes are the Conventional Commits types models are expected to use
+var CommonTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+var (
+	headerPattern = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()\r\n]*)\))?(!)?:\s*(.+)$`)
+	footerPattern = regexp.MustCompile(`^(BREAKING[ -]CHANGE|[A-Za-z][A-Za-z0-9-]*)(?:: | (#))(.*)$`)
+)
+
+// Parse extracts a commit message from model output. It accepts a git commit
+// shell command, fenced code blocks, JSON objects and plain text, and ignores
+// surrounding prose where it can.
+func Parse(raw string) (*Message, error) {
+	text := strings.TrimSpace(raw)
+	if block, ok := fencedBlock(text); ok {
+		text = block
+	}
+	if text == "" {
+		return nil, ErrEmpty
+	}
+
+	m, ok := parseJSON(text)
+	if !ok {
+		m, ok = parseShell(text)
+	}
+	if !ok {
+		var err error
+		if m, err = parsePlain(text); err != nil {
+			return nil, err
+		}
+	}
+
+	// Models capitalise types now and then; normalise them
+	m.Type = strings.ToLower(m.Type)
+	return m, nil
+}
+
+// ParseText parses a message written by a person, such as an edited commit
+// message: the first non-empty line is always the header
+func ParseText(text string) (*Message, error) {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return nil, ErrEmpty
+	}
+
+	header, rest, _ := strings.Cut(text, "\n")
+	m := ParseHeader(header)
+	parseRest(m, rest)
+	return m, nil
+}
+
+// ParseHeader splits a header line into type, scope, breaking flag and
+// subject. Lines that do not follow the convention become the subject.
+func ParseHeader(line string) *Message {
+	line = strings.TrimSpace(line)
+	match := headerPattern.FindStringSubmatch(line)
+	if match == nil {
+		return &Message{Subject: line}
+	}
+	return &Message{
+		Type:     match[1],
+		Scope:    strings.TrimSpace(match[2]),
+		Breaking: match[3] == "!",
+		Subject:  strings.TrimSpace(match[4]),
+	}
+}
+
+// fencedBlock returns the content of the first ``` fenced block in text
+func fencedBlock(text string) (string, bool) {
+	start := strings.Index(text, "```")
+	if start < 0 {
+		return "", false
+	}
+	rest := text[start+3:]
+
+	// Skip the info string (e.g. "json" or "bash")
+	if nl := strings.IndexByte(rest, '\n'); nl >= 0 {
+		rest = rest[nl+1:]
+	} else {
+		rest = ""
+	}
+	if end := strings.Index(rest, "```"); end >= 0 {
+		rest = rest[:end]
+	}
+	return strings.TrimSpace(rest), true
+}
+
+type jsonMessage struct {
+	Type        string          `json:"type"`
+	Scope       string          `json:"scope"`
+	Breaking    bool            `json:"breaking"`
+	Header      string          `json:"header"`
+	Title       string          `json:"title"`
+	Subject     string          `json:"subject"`
+	Description string          `json:"description"`
+	Body        string          `json:"body"`
+	Message     string          `json:"message"`
+	Footers     json.RawMessage `json:"footers"`
+}
+
+func parseJSON(text string) (*Message, bool) {
+	start, end := strings.IndexByte(text, '{'), strings.LastIndexByte(text, '}')
+	if start < 0 || end <= start {
+		return nil, false
+	}
+
+	var jm jsonMessage
+	if err := json.Unmarshal([]byte(text[start:end+1]), &jm); err != nil {
+		return nil, false
+	}
+
+	if jm.Message != "" && jm.Subject == "" && jm.Header == "" && jm.Title == "" {
+		m, err := parsePlain(jm.Message)
+		return m, err == nil
+	}
+
+	// "description" is the subject in the spec's vocabulary, but the body in
+	// the -m "<title>" -m "<description>" form the prompt asks for
+	header := firstNonEmpty(jm.Header, jm.Title)
+	body := jm.Body
+	if header != "" && body == "" {
+		body = jm.Description
+	}
+	subject := firstNonEmpty(jm.Subject, jm.Description)
+
+	var m *Message
+	switch {
+	case header != "":
+		m = ParseHeader(header)
+	case subject != "" && jm.Type != "":
+		m = &Message{Subject: subject}
+	case subject != "":
+		m = ParseHeader(subject)
+	default:
+		return nil, false
+	}
+
+	if jm.Type != "" {
+		m.Type = strings.ToLower(strings.TrimSpace(jm.Type))
+	}
+	if jm.Scope != "" {
+		m.Scope = strings.TrimSpace(jm.Scope)
+	}
+	m.Breaking = m.Breaking || jm.Breaking
+	parseRest(m, body)
+	m.Footers = append(m.Footers, parseJSONFooters(jm.Footers)...)
+	markBreaking(m)
+	return m, true
+}
+
+func parseJSONFooters(raw json.RawMessage) []Footer {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var list []Footer
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list
+	}
+
+	var lines []string
+	if err := json.Unmarshal(raw, &lines); err == nil {
+		var footers []Footer
+		for _, line := range lines {
+			if f, ok := ParseFooter(line); ok {
+				footers = append(footers, f)
+			}
+		}
+		return footers
+	}
+
+	// An object is read key by key to keep the trailers in the model's order
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+	var footers []Footer
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		var value string
+		if err := dec.Decode(&value); err != nil {
+			return nil
+		}
+		footers = append(footers, Footer{Token: tok.(string), Value: value})
+	}
+	return footers
+}
+
+func parseShell(text string) (*Message, bool) {
+	idx := strings.Index(text, "git commit")
+	if idx < 0 {
+		return nil, false
+	}
+
+	args := shellWords(text[idx+len("git commit"):])
+	var parts []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-m" || arg == "--message":
+			if i+1 < len(args) {
+				parts = append(parts, args[i+1])
+				i++
+			}
+		case strings.HasPrefix(arg, "--message="):
+			parts = append(parts, strings.TrimPrefix(arg, "--message="))
+		case strings.HasPrefix(arg, "-m") && !strings.HasPrefix(arg, "--"):
+			parts = append(parts, strings.TrimPrefix(arg, "-m"))
+		}
+	}
+	if len(parts) == 0 || strings.TrimSpace(parts[0]) == "" {
+		return nil, false
+	}
+
+	header, rest, _ := strings.Cut(strings.TrimSpace(parts[0]), "\n")
+	m := ParseHeader(header)
+
+	var body []string
+	if strings.TrimSpace(rest) != "" {
+		body = append(body, strings.TrimSpace(rest))
+	}
+	for _, p := range parts[1:] {
+		if p = strings.TrimSpace(p); p != "" {
+			body = append(body, p)
+		}
+	}
+	parseRest(m, strings.Join(body, "\n\n"))
+	return m, true
+}
+
+// shellWords splits a command line the way a POSIX shell would, but never
+// fails: unterminated quotes run to the end of the input and the command ends
+// at the first unquoted newline, ";" or "&&".
+func shellWords(s string) []string {
+	var (
+		words   []string
+		current strings.Builder
+		inWord  bool
+	)
+	flush := func() {
+		if inWord {
+			words = append(words, current.String())
+			current.Reset()
+			inWord = false
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			i++
+		case c == '\n' || c == ';' || (c == '&' && i+1 < len(s) && s[i+1] == '&'):
+			flush()
+			return words
+		case c == ' ' || c == '\t' || c == '\r':
+			flush()
+		case c == '\'':
+			inWord = true
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				current.WriteString(s[i+1:])
+				i = len(s)
+			} else {
+				current.WriteString(s[i+1 : i+1+end])
+				i += end + 1
+			}
+		case c == '"':
+			inWord = true
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					switch s[i+1] {
+					case '"', '\\', '$', '`':
+						i++
+					case 'n':
+						// Models often mean a newline when they write \n
+						current.WriteByte('\n')
+						i++
+						continue
+					}
+				}
+				current.WriteByte(s[i])
+			}
+		case c == '\\' && i+1 < len(s):
+			inWord = true
+			i++
+			current.WriteByte(s[i])
+		default:
+			inWord = true
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return words
+}
+
+func parsePlain(text string) (*Message, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	// Skip prose until the first line that looks like a conventional header,
+	// falling back to the first line that is not an introduction
+	start := -1
+	for i, line := range lines {
+		if m := ParseHeader(cleanLine(line)); isKnownType(strings.ToLower(m.Type)) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		for i, line := range lines {
+			clean := cleanLine(line)
+			if clean != "" && !strings.HasSuffix(clean, ":") {
+				start = i
+				break
+			}
+		}
+	}
+	if start < 0 {
+		return nil, ErrEmpty
+	}
+
+	m := ParseHeader(cleanLine(lines[start]))
+	parseRest(m, strings.Join(lines[start+1:], "\n"))
+	return m, nil
+}
+
+// parseRest fills body and footers from the text following the header
+func parseRest(m *Message, text string) {
+	paragraphs := splitParagraphs(text)
+	if n := len(paragraphs); n > 0 {
+		if footers, ok := parseFooters(paragraphs[n-1]); ok {
+			m.Footers = append(m.Footers, footers...)
+			paragraphs = paragraphs[:n-1]
+		}
+	}
+	m.Body = strings.Join(paragraphs, "\n\n")
+	markBreaking(m)
+}
+
+func splitParagraphs(text string) []string {
+	var (
+		paragraphs []string
+		current    []string
+	)
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				paragraphs = append(paragraphs, strings.Join(current, "\n"))
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		paragraphs = append(paragraphs, strings.Join(current, "\n"))
+	}
+	return paragraphs
+}
+
+// parseFooters parses a paragraph made only of trailers. Lines that do not
+// start a new trailer continue the value of the previous one.
+func parseFooters(paragraph string) ([]Footer, bool) {
+	var footers []Footer
+	for _, line := range strings.Split(paragraph, "\n") {
+		if f, ok := ParseFooter(line); ok {
+			footers = append(footers, f)
+			continue
+		}
+		if len(footers) == 0 {
+			return nil, false
+		}
+		footers[len(footers)-1].Value += "\n" + line
+	}
+	return footers, len(footers) > 0
+}
+
+// ParseFooter parses a single trailer line such as "Refs: PROJ-1"
+func ParseFooter(line string) (Footer, bool) {
+	match := footerPattern.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return Footer{}, false
+	}
+	token := match[1]
+	if strings.HasPrefix(token, "BREAKING") {
+		token = "BREAKING CHANGE"
+	}
+	return Footer{Token: token, Value: match[2] + strings.TrimSpace(match[3])}, true
+}
+
+func markBreaking(m *Message) {
+	if _, ok := m.Footer("BREAKING CHANGE"); ok {
+		m.Breaking = true
+	}
+}
+
+// cleanLine strips list markers, emphasis and quotes models wrap headers in
+func cleanLine(line string) string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimLeft(line, "-*> ")
+	line = strings.Trim(line, "*_`")
+	if len(line) >= 2 && (line[0] == '"' || line[0] == '\'') && line[len(line)-1] == line[0] {
+		line = line[1 : len(line)-1]
+	}
+	return strings.TrimSpace(line)
+}
+
+func isKnownType(t string) bool {
+	for _, known := range CommonTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,316 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPort is the port the Ollama server listens on by default
+const DefaultPort = "11434"
+
+// Client talks to an Ollama server over its HTTP API
+type Client struct {
+	// BaseURL is the server address, e.g. http://127.0.0.1:11434
+	BaseURL string
+
+	// HTTPClient is the client used to issue requests
+	HTTPClient *http.Client
+}
+
+// Message is a single chat message
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// GenerateRequest is the body of a /api/generate call
+type GenerateRequest struct {
+	Model   string         `json:"model"`
+	Prompt  string         `json:"prompt"`
+	System  string         `json:"system,omitempty"`
+	Stream  bool           `json:"stream"`
+	Options map[string]any `json:"options,omitempty"`
+}
+
+// GenerateResponse is the body returned by /api/generate
+type GenerateResponse struct {
+	Model              string `json:"model"`
+	Response           string `json:"response"`
+	Done               bool   `json:"done"`
+	DoneReason         string `json:"done_reason,omitempty"`
+	TotalDuration      int64  `json:"total_duration,omitempty"`
+	PromptEvalCount    int    `json:"prompt_eval_count,omitempty"`
+	EvalCount          int    `json:"eval_count,omitempty"`
+	EvalDuration       int64  `json:"eval_duration,omitempty"`
+	PromptEvalDuration int64  `json:"prompt_eval_duration,omitempty"`
+}
+
+// ChatRequest is the body of a /api/chat call
+type ChatRequest struct {
+	Model     string         `json:"model"`
+	Messages  []Message      `json:"messages"`
+	Stream    bool           `json:"stream"`
+	Options   map[string]any `json:"options,omitempty"`
+	KeepAlive string         `json:"keep_alive,omitempty"`
+}
+
+// ChatResponse is the body returned by /api/chat
+type ChatResponse struct {
+	Model              string  `json:"model"`
+	Message            Message `json:"message"`
+	Done               bool    `json:"done"`
+	DoneReason         string  `json:"done_reason,omitempty"`
+	TotalDuration      int64   `json:"total_duration,omitempty"`
+	PromptEvalCount    int     `json:"prompt_eval_count,omitempty"`
+	EvalCount          int     `json:"eval_count,omitempty"`
+	EvalDuration       int64   `json:"eval_duration,omitempty"`
+	PromptEvalDuration int64   `json:"prompt_eval_duration,omitempty"`
+}
+
+// NewClient creates a client for the given base URL. An empty base URL falls
+// back to the OLLAMA_HOST environment variable and then to the local default.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    ResolveBaseURL(baseURL),
+		HTTPClient: &http.Client{},
+	}
+}
+
+// ResolveBaseURL normalizes a host specification the same way the ollama CLI
+// interprets OLLAMA_HOST: scheme and port are optional.
+func ResolveBaseURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		raw = strings.TrimSpace(os.Getenv("OLLAMA_HOST"))
+	}
+
+	defaultPort := DefaultPort
+	scheme, hostport, ok := strings.Cut(raw, "://")
+	switch {
+	case !ok:
+		scheme, hostport = "http", raw
+	case scheme == "http":
+		defaultPort = "80"
+	case scheme == "https":
+		defaultPort = "443"
+	}
+
+	hostport, path, _ := strings.Cut(hostport, "/")
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host, port = "127.0.0.1", defaultPort
+		if ip := net.ParseIP(strings.Trim(hostport, "[]")); ip != nil {
+			host = ip.String()
+		} else if hostport != "" {
+			host = hostport
+		}
+	}
+
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		port = defaultPort
+	}
+
+	u := url.URL{Scheme: scheme, Host: net.JoinHostPort(host, port)}
+	if path != "" {
+		u.Path = "/" + strings.TrimSuffix(path, "/")
+	}
+	return u.String()
+}
+
+// Heartbeat checks that the server is up and answering requests
+func (c *Client) Heartbeat(ctx context.Context) error {
+	return c.do(ctx, http.MethodHead, "/", nil, nil)
+}
+
+// CheckInstallation verifies that the client's Ollama server is reachable
+func (c *Client) CheckInstallation(ctx context.Context) error {
+	if err := c.Heartbeat(ctx); err != nil {
+		return fmt.Errorf("ollama is not reachable at %s. Please start Ollama (ollama serve) or set OLLAMA_HOST: %w", c.BaseURL, err)
+	}
+	return nil
+}
+
+// Generate runs a single-prompt completion through /api/generate
+func (c *Client) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	if req.Model == "" {
+		return nil, fmt.Errorf("model name is required")
+	}
+	req.Stream = false
+
+	var resp GenerateResponse
+	if err := c.do(ctx, http.MethodPost, "/api/generate", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Chat runs a conversation through /api/chat
+func (c *Client) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if req.Model == "" {
+		return nil, fmt.Errorf("model name is required")
+	}
+	req.Stream = false
+
+	var resp ChatResponse
+	if err := c.do(ctx, http.MethodPost, "/api/chat", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	resp, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode ollama response: %v", err)
+	}
+	return nil
+}
+
+// send issues the request and converts transport failures and non-2xx
+// responses into typed errors. The caller must close the response body.
+func (c *Client) send(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode ollama request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build ollama request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("%w at %s: %v", ErrServerUnreachable, c.BaseURL, unwrapURLError(err))
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return nil, readAPIError(resp)
+	}
+	return resp, nil
+}
+
+func readAPIError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &payload); err == nil && payload.Error != "" {
+		apiErr.Message = payload.Error
+	} else {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	return apiErr
+}
+
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// ModelDetails describes the format of a local model
+type ModelDetails struct {
+	Format            string `json:"format,omitempty"`
+	Family            string `json:"family,omitempty"`
+	ParameterSize     string `json:"parameter_size,omitempty"`
+	QuantizationLevel string `json:"quantization_level,omitempty"`
+}
+
+// ModelInfo is an entry returned by /api/tags
+type ModelInfo struct {
+	Name       string       `json:"name"`
+	Model      string       `json:"model"`
+	ModifiedAt time.Time    `json:"modified_at"`
+	Size       int64        `json:"size"`
+	Digest     string       `json:"digest"`
+	Details    ModelDetails `json:"details"`
+}
+
+// ListResponse is the body returned by /api/tags
+type ListResponse struct {
+	Models []ModelInfo `json:"models"`
+}
+
+// List returns the models available on the server
+func (c *Client) List(ctx context.Context) (*ListResponse, error) {
+	var resp ListResponse
+	if err := c.do(ctx, http.MethodGet, "/api/tags", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ShowRequest is the body of a /api/show call
+type ShowRequest struct {
+	Model string `json:"model"`
+}
+
+// ShowResponse is the body returned by /api/show
+type ShowResponse struct {
+	Details ModelDetails `json:"details"`
+
+	// ModelInfo holds the model's metadata, keyed by names such as
+	// "llama.context_length"
+	ModelInfo map[string]any `json:"model_info"`
+}
+
+// ContextLength returns the longest context the model was trained for, or
+// zero when the server does not say
+func (r *ShowResponse) ContextLength() int {
+	for key, value := range r.ModelInfo {
+		if n, ok := value.(float64); ok && strings.HasSuffix(key, ".context_length") {
+			return int(n)
+		}
+	}
+	return 0
+}
+
+// Show returns the details of a local model
+func (c *Client) Show(ctx context.Context, req ShowRequest) (*ShowResponse, error) {
+	var resp ShowResponse
+	if err := c.do(ctx, http.MethodPost, "/api/show", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
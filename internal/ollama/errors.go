@@ -0,0 +1,65 @@
+package ollama
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrModelNotFound is returned when the requested model is not available on the server
+	ErrModelNotFound = errors.New("model not found")
+
+	// ErrServerUnreachable is returned when the Ollama server cannot be contacted
+	ErrServerUnreachable = errors.New("ollama server unreachable")
+
+	// ErrContextOverflow is returned when the prompt does not fit in the model context window
+	ErrContextOverflow = errors.New("prompt exceeds the model context length")
+
+	// ErrTemporary is returned when the server fails in a way that may pass,
+	// such as a 5xx status or too many requests
+	ErrTemporary = errors.New("temporary server error")
+)
+
+// APIError is an error response returned by the Ollama HTTP API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ollama API error: %s", http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("ollama API error (%d): %s", e.StatusCode, e.Message)
+}
+
+// Unwrap maps the API error onto one of the package sentinel errors, if any applies
+func (e *APIError) Unwrap() error {
+	msg := strings.ToLower(e.Message)
+
+	switch {
+	case e.StatusCode == http.StatusNotFound && (msg == "" || strings.Contains(msg, "model")):
+		return ErrModelNotFound
+	case strings.Contains(msg, "model") && strings.Contains(msg, "not found"):
+		return ErrModelNotFound
+	case contextOverflow(msg):
+		return ErrContextOverflow
+	case e.StatusCode >= http.StatusInternalServerError,
+		e.StatusCode == http.StatusTooManyRequests,
+		e.StatusCode == http.StatusRequestTimeout:
+		return ErrTemporary
+	}
+	return nil
+}
+
+// contextOverflow reports whether msg is the server saying the prompt does
+// not fit in the context window, e.g. "input length exceeds maximum context
+// length" or "the input length exceeds the context length"
+func contextOverflow(msg string) bool {
+	if !strings.Contains(msg, "context length") && !strings.Contains(msg, "context window") {
+		return false
+	}
+	return strings.Contains(msg, "exceed") || strings.Contains(msg, "too long")
+}
@@ -0,0 +1,343 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(server.URL)
+}
+
+func TestResolveBaseURL(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		env  string
+		want string
+	}{
+		{
+			name: "should use local default when nothing is configured",
+			want: "http://127.0.0.1:11434",
+		},
+		{
+			name: "should honour OLLAMA_HOST",
+			env:  "gpu-box:8080",
+			want: "http://gpu-box:8080",
+		},
+		{
+			name: "should prefer explicit base URL over OLLAMA_HOST",
+			raw:  "https://ollama.example.com",
+			env:  "gpu-box:8080",
+			want: "https://ollama.example.com:443",
+		},
+		{
+			name: "should add default port to bare host",
+			raw:  "gpu-box",
+			want: "http://gpu-box:11434",
+		},
+		{
+			name: "should keep path prefix",
+			raw:  "http://proxy.local:80/ollama/",
+			want: "http://proxy.local:80/ollama",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OLLAMA_HOST", tt.env)
+			if got := ResolveBaseURL(tt.raw); got != tt.want {
+				t.Errorf("ResolveBaseURL(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/generate" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		var req GenerateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if req.Stream {
+			t.Error("Generate() should disable streaming")
+		}
+		if req.Options["temperature"] != 0.2 {
+			t.Errorf("Generate() options = %v, want temperature 0.2", req.Options)
+		}
+
+		json.NewEncoder(w).Encode(GenerateResponse{Model: req.Model, Response: "feat: " + req.Prompt, Done: true})
+	})
+
+	resp, err := client.Generate(context.Background(), GenerateRequest{
+		Model:   "llama3.1",
+		Prompt:  "hello",
+		Options: map[string]any{"temperature": 0.2},
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if resp.Response != "feat: hello" {
+		t.Errorf("Generate() response = %q, want %q", resp.Response, "feat: hello")
+	}
+}
+
+func TestChat(t *testing.T) {
+	client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		var req ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		last := req.Messages[len(req.Messages)-1]
+		json.NewEncoder(w).Encode(ChatResponse{
+			Model:   req.Model,
+			Message: Message{Role: "assistant", Content: strings.ToUpper(last.Content)},
+			Done:    true,
+		})
+	})
+
+	resp, err := client.Chat(context.Background(), ChatRequest{
+		Model:    "llama3.1",
+		Messages: []Message{{Role: "system", Content: "be brief"}, {Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if resp.Message.Content != "HI" {
+		t.Errorf("Chat() content = %q, want %q", resp.Message.Content, "HI")
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+	}{
+		{
+			name:    "should report missing model",
+			status:  http.StatusNotFound,
+			body:    `{"error":"model 'nope' not found, try pulling it first"}`,
+			wantErr: ErrModelNotFound,
+		},
+		{
+			name:    "should report context overflow",
+			status:  http.StatusBadRequest,
+			body:    `{"error":"input length exceeds maximum context length"}`,
+			wantErr: ErrContextOverflow,
+		},
+		{
+			name:    "should report server failures as temporary",
+			status:  http.StatusInternalServerError,
+			body:    `{"error":"out of memory"}`,
+			wantErr: ErrTemporary,
+		},
+		{
+			name:   "should report other API errors",
+			status: http.StatusBadRequest,
+			body:   `{"error":"invalid options"}`,
+		},
+		{
+			name:   "should not mistake other lengths for context overflow",
+			status: http.StatusBadRequest,
+			body:   `{"error":"model name is too long"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			_, err := client.Generate(context.Background(), GenerateRequest{Model: "nope", Prompt: "x"})
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Generate() error = %v, want *APIError", err)
+			}
+			if apiErr.StatusCode != tt.status {
+				t.Errorf("APIError.StatusCode = %d, want %d", apiErr.StatusCode, tt.status)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Generate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && apiErr.Unwrap() != nil {
+				t.Errorf("APIError.Unwrap() = %v, want nil", apiErr.Unwrap())
+			}
+		})
+	}
+}
+
+func TestServerUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	baseURL := server.URL
+	server.Close()
+
+	client := NewClient(baseURL)
+	if err := client.CheckInstallation(context.Background()); !errors.Is(err, ErrServerUnreachable) {
+		t.Errorf("CheckInstallation() error = %v, want %v", err, ErrServerUnreachable)
+	}
+	if _, err := client.Generate(context.Background(), GenerateRequest{Model: "llama3.1", Prompt: "x"}); !errors.Is(err, ErrServerUnreachable) {
+		t.Errorf("Generate() error = %v, want %v", err, ErrServerUnreachable)
+	}
+}
+
+func TestGenerateRequiresModel(t *testing.T) {
+	client := NewClient("http://127.0.0.1:1")
+	if _, err := client.Generate(context.Background(), GenerateRequest{Prompt: "x"}); err == nil {
+		t.Error("Generate() should fail without a model")
+	}
+}
+
+func TestGenerateStream(t *testing.T) {
+	tests := []struct {
+		name       string
+		lines      []string
+		wantTokens []string
+		wantText   string
+		wantErr    bool
+	}{
+		{
+			name: "should deliver tokens in order",
+			lines: []string{
+				`{"response":"feat: ","done":false}`,
+				`{"response":"add login","done":false}`,
+				`{"response":"","done":true,"eval_count":3}`,
+			},
+			wantTokens: []string{"feat: ", "add login"},
+			wantText:   "feat: add login",
+		},
+		{
+			name: "should surface in-band errors",
+			lines: []string{
+				`{"response":"feat","done":false}`,
+				`{"error":"model runner has unexpectedly stopped"}`,
+			},
+			wantTokens: []string{"feat"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				var req GenerateRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				if !req.Stream {
+					t.Error("GenerateStream() should request streaming")
+				}
+				for _, line := range tt.lines {
+					w.Write([]byte(line + "\n"))
+				}
+			})
+
+			var tokens []string
+			resp, err := client.GenerateStream(context.Background(), GenerateRequest{Model: "llama3.1", Prompt: "x"}, func(token string) error {
+				tokens = append(tokens, token)
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateStream() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(tokens, "|") != strings.Join(tt.wantTokens, "|") {
+				t.Errorf("GenerateStream() tokens = %q, want %q", tokens, tt.wantTokens)
+			}
+			if !tt.wantErr && resp.Response != tt.wantText {
+				t.Errorf("GenerateStream() text = %q, want %q", resp.Response, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestShow(t *testing.T) {
+	client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/show" {
+			t.Errorf("path = %q, want /api/show", r.URL.Path)
+		}
+		w.Write([]byte(`{"details":{"quantization_level":"Q4_K_M"},"model_info":{"general.architecture":"qwen2","qwen2.context_length":32768}}`))
+	})
+
+	resp, err := client.Show(context.Background(), ShowRequest{Model: "qwen2.5-coder:7b"})
+	if err != nil {
+		t.Fatalf("Show() error = %v", err)
+	}
+	if got := resp.ContextLength(); got != 32768 {
+		t.Errorf("ContextLength() = %d, want 32768", got)
+	}
+	if resp.Details.QuantizationLevel != "Q4_K_M" {
+		t.Errorf("Show() quantization = %q, want Q4_K_M", resp.Details.QuantizationLevel)
+	}
+}
+
+func TestPull(t *testing.T) {
+	tests := []struct {
+		name         string
+		lines        []string
+		wantStatuses []string
+		wantErr      bool
+	}{
+		{
+			name: "should report the progress of each step",
+			lines: []string{
+				`{"status":"pulling manifest"}`,
+				`{"status":"pulling 6a0746a1ec1a","digest":"sha256:6a0746a1ec1a","total":1000,"completed":500}`,
+				`{"status":"success"}`,
+			},
+			wantStatuses: []string{"pulling manifest", "pulling 6a0746a1ec1a", "success"},
+		},
+		{
+			name: "should surface in-band errors",
+			lines: []string{
+				`{"status":"pulling manifest"}`,
+				`{"error":"pull model manifest: file does not exist"}`,
+			},
+			wantStatuses: []string{"pulling manifest"},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				var req PullRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				if !req.Stream {
+					t.Error("Pull() should request streaming")
+				}
+				for _, line := range tt.lines {
+					w.Write([]byte(line + "\n"))
+				}
+			})
+
+			var statuses []string
+			err := client.Pull(context.Background(), PullRequest{Model: "llama3.1"}, func(resp PullResponse) error {
+				statuses = append(statuses, resp.Status)
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Pull() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(statuses, "|") != strings.Join(tt.wantStatuses, "|") {
+				t.Errorf("Pull() statuses = %q, want %q", statuses, tt.wantStatuses)
+			}
+		})
+	}
+}
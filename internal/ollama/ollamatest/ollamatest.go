@@ -0,0 +1,316 @@
+// Package ollamatest provides an in-process stand-in for the Ollama HTTP API
+// so that packages depending on ollama can be tested without a real server.
+package ollamatest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// DefaultResponse is the completion returned when none is configured
+const DefaultResponse = `git commit -m "test: add mock commit message" -m "This is a test commit message"`
+
+// Server is a fake Ollama server
+type Server struct {
+	*httptest.Server
+
+	// Response is the completion returned by /api/generate and /api/chat
+	Response string
+
+	// Responses, when set, are returned one per request before falling back
+	// to Response
+	Responses []string
+
+	// Models lists the model names the server knows; empty accepts any
+	// model. As with Ollama, a name without a tag stands for its "latest" tag.
+	Models []string
+
+	// ContextLength is the context length /api/show reports for every model
+	ContextLength int
+
+	// Registry lists the models /api/pull can download; empty allows any.
+	// Pulled models are added to Models.
+	Registry []string
+
+	// Pulls records the model of each pull request, in order
+	Pulls []string
+
+	// Failures, when set, are status codes answered one per generation
+	// request for a known model before the server responds normally
+	Failures []int
+
+	// Asked records the model of each generation request, in order
+	Asked []string
+
+	// Delay holds back each generation response, or until the client gives
+	// up
+	Delay time.Duration
+
+	// Requests counts the generation requests received
+	Requests atomic.Int32
+
+	// Prompts records the prompt of each generation request, or the last
+	// message of each chat request
+	Prompts []string
+
+	// Options records the "options" object of each chat request, and
+	// Systems its system prompt
+	Options []map[string]any
+	Systems []string
+
+	mu sync.Mutex
+}
+
+// NewServer starts a fake Ollama server, points OLLAMA_HOST at it for the
+// duration of the test and shuts it down on cleanup
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{Response: DefaultResponse}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("Ollama is running"))
+	})
+	mux.HandleFunc("/api/tags", func(w http.ResponseWriter, r *http.Request) {
+		models := []map[string]any{}
+		s.mu.Lock()
+		for _, name := range s.Models {
+			models = append(models, map[string]any{
+				"name":    name,
+				"model":   name,
+				"size":    4661224676,
+				"details": map[string]string{"family": "llama", "parameter_size": "8.0B", "quantization_level": "Q4_K_M"},
+			})
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]any{"models": models})
+	})
+	mux.HandleFunc("/api/show", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if !s.hasModel(req.Model) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "model '" + req.Model + "' not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"details":    map[string]string{"family": "llama", "parameter_size": "8.0B", "quantization_level": "Q4_K_M"},
+			"model_info": map[string]any{"general.architecture": "llama", "llama.context_length": s.ContextLength},
+		})
+	})
+	mux.HandleFunc("/api/pull", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		s.mu.Lock()
+		s.Pulls = append(s.Pulls, req.Model)
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		enc.Encode(map[string]any{"status": "pulling manifest"})
+		if len(s.Registry) > 0 && !contains(s.Registry, req.Model) {
+			enc.Encode(map[string]string{"error": "pull model manifest: file does not exist"})
+			return
+		}
+		for _, completed := range []int{0, 500, 1000} {
+			enc.Encode(map[string]any{"status": "pulling 6a0746a1ec1a", "digest": "sha256:6a0746a1ec1a", "total": 1000, "completed": completed})
+		}
+		enc.Encode(map[string]any{"status": "verifying sha256 digest"})
+		enc.Encode(map[string]any{"status": "writing manifest"})
+		s.mu.Lock()
+		if len(s.Models) > 0 && !contains(s.Models, req.Model) {
+			s.Models = append(s.Models, req.Model)
+		}
+		s.mu.Unlock()
+		enc.Encode(map[string]any{"status": "success"})
+	})
+	mux.HandleFunc("/api/generate", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model  string `json:"model"`
+			Prompt string `json:"prompt"`
+			Stream bool   `json:"stream"`
+		}
+		if !s.accept(w, r, &req.Model, &req) {
+			return
+		}
+		s.record(req.Prompt)
+		response := s.next()
+		if req.Stream {
+			writeStream(w, response, func(token string, done bool) any {
+				return map[string]any{"model": req.Model, "response": token, "done": done}
+			})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"model": req.Model, "response": response, "done": true})
+	})
+	mux.HandleFunc("/api/chat", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+			Stream  bool           `json:"stream"`
+			Options map[string]any `json:"options"`
+		}
+		if !s.accept(w, r, &req.Model, &req) {
+			return
+		}
+		var system string
+		if len(req.Messages) > 0 && req.Messages[0].Role == "system" {
+			system = req.Messages[0].Content
+		}
+		s.mu.Lock()
+		s.Options = append(s.Options, req.Options)
+		s.Systems = append(s.Systems, system)
+		s.mu.Unlock()
+		if len(req.Messages) > 0 {
+			s.record(req.Messages[len(req.Messages)-1].Content)
+		}
+		response := s.next()
+
+		// The final chunk counts the words of the conversation and of the
+		// response as tokens
+		var promptTokens int
+		for _, m := range req.Messages {
+			promptTokens += len(strings.Fields(m.Content))
+		}
+		chunk := func(token string, done bool) any {
+			c := map[string]any{
+				"model":   req.Model,
+				"message": map[string]string{"role": "assistant", "content": token},
+				"done":    done,
+			}
+			if done {
+				c["prompt_eval_count"] = promptTokens
+				c["eval_count"] = len(strings.Fields(response))
+			}
+			return c
+		}
+		if req.Stream {
+			writeStream(w, response, chunk)
+			return
+		}
+		writeJSON(w, http.StatusOK, chunk(response, true))
+	})
+
+	s.Server = httptest.NewServer(mux)
+	t.Setenv("OLLAMA_HOST", s.URL)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *Server) accept(w http.ResponseWriter, r *http.Request, model *string, req any) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return false
+	}
+	s.mu.Lock()
+	s.Asked = append(s.Asked, *model)
+	s.mu.Unlock()
+	if !s.hasModel(*model) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "model '" + *model + "' not found"})
+		return false
+	}
+	if status := s.failure(); status != 0 {
+		writeJSON(w, status, map[string]string{"error": http.StatusText(status)})
+		return false
+	}
+	s.Requests.Add(1)
+	if s.Delay > 0 {
+		select {
+		case <-time.After(s.Delay):
+		case <-r.Context().Done():
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) record(prompt string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Prompts = append(s.Prompts, prompt)
+}
+
+// failure returns the status code the current request fails with, or zero
+func (s *Server) failure() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.Failures) == 0 {
+		return 0
+	}
+	status := s.Failures[0]
+	s.Failures = s.Failures[1:]
+	return status
+}
+
+// next returns the completion for the current request
+func (s *Server) next() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.Responses) == 0 {
+		return s.Response
+	}
+	response := s.Responses[0]
+	s.Responses = s.Responses[1:]
+	return response
+}
+
+// writeStream sends the response as newline-delimited JSON, one word per chunk
+func writeStream(w http.ResponseWriter, response string, chunk func(token string, done bool) any) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	for _, token := range strings.SplitAfter(response, " ") {
+		enc.Encode(chunk(token, false))
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	enc.Encode(chunk("", true))
+}
+
+func (s *Server) hasModel(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.Models) == 0 {
+		return true
+	}
+	for _, m := range s.Models {
+		if m == name || !strings.Contains(name, ":") && m == name+":latest" {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
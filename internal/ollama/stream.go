@@ -0,0 +1,162 @@
+package ollama
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TokenFunc receives each chunk of text as the model produces it. Returning
+// an error aborts the stream.
+type TokenFunc func(token string) error
+
+// GenerateStream runs a completion through /api/generate, calling fn for each
+// token as it arrives. The returned response carries the full text and the
+// statistics reported with the final chunk.
+func (c *Client) GenerateStream(ctx context.Context, req GenerateRequest, fn TokenFunc) (*GenerateResponse, error) {
+	if req.Model == "" {
+		return nil, fmt.Errorf("model name is required")
+	}
+	req.Stream = true
+
+	var (
+		text  strings.Builder
+		final GenerateResponse
+	)
+	err := c.stream(ctx, "/api/generate", req, func(line []byte) error {
+		var chunk GenerateResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("failed to decode ollama response: %v", err)
+		}
+		text.WriteString(chunk.Response)
+		if chunk.Done {
+			final = chunk
+		}
+		if chunk.Response == "" || fn == nil {
+			return nil
+		}
+		return fn(chunk.Response)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	final.Response = text.String()
+	return &final, nil
+}
+
+// ChatStream runs a conversation through /api/chat, calling fn for each token
+// as it arrives. The returned response carries the full assistant message.
+func (c *Client) ChatStream(ctx context.Context, req ChatRequest, fn TokenFunc) (*ChatResponse, error) {
+	if req.Model == "" {
+		return nil, fmt.Errorf("model name is required")
+	}
+	req.Stream = true
+
+	var (
+		text  strings.Builder
+		final ChatResponse
+	)
+	err := c.stream(ctx, "/api/chat", req, func(line []byte) error {
+		var chunk ChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("failed to decode ollama response: %v", err)
+		}
+		text.WriteString(chunk.Message.Content)
+		if chunk.Done {
+			final = chunk
+		}
+		if chunk.Message.Content == "" || fn == nil {
+			return nil
+		}
+		return fn(chunk.Message.Content)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	final.Message.Role = "assistant"
+	final.Message.Content = text.String()
+	return &final, nil
+}
+
+// stream posts body to path and hands every newline-delimited JSON object of
+// the response to handle. Errors reported in-band by the server are returned
+// as *APIError.
+func (c *Client) stream(ctx context.Context, path string, body any, handle func(line []byte) error) error {
+	resp, err := c.send(ctx, http.MethodPost, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), 8<<20)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+
+		var inband struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(line, &inband); err == nil && inband.Error != "" {
+			return &APIError{StatusCode: resp.StatusCode, Message: inband.Error}
+		}
+
+		if err := handle(line); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return fmt.Errorf("failed to read ollama stream: %v", err)
+	}
+	return nil
+}
+
+// PullRequest is the body of a /api/pull call
+type PullRequest struct {
+	Model  string `json:"model"`
+	Stream bool   `json:"stream"`
+}
+
+// PullResponse is a progress update of /api/pull. Total and Completed count
+// the bytes of the layer being downloaded.
+type PullResponse struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+}
+
+// PullFunc receives each progress update of a pull. Returning an error
+// aborts it.
+type PullFunc func(PullResponse) error
+
+// Pull downloads a model from the registry, calling fn with each progress
+// update
+func (c *Client) Pull(ctx context.Context, req PullRequest, fn PullFunc) error {
+	if req.Model == "" {
+		return fmt.Errorf("model name is required")
+	}
+	req.Stream = true
+
+	return c.stream(ctx, "/api/pull", req, func(line []byte) error {
+		var update PullResponse
+		if err := json.Unmarshal(line, &update); err != nil {
+			return fmt.Errorf("failed to decode ollama response: %v", err)
+		}
+		if fn == nil {
+			return nil
+		}
+		return fn(update)
+	})
+}
@@ -0,0 +1,337 @@
+// Package openai is a client for servers speaking the OpenAI-compatible
+// chat completions protocol, such as llama.cpp server, vLLM, LM Studio and
+// LocalAI.
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultBaseURL is the address of a local llama.cpp server
+const DefaultBaseURL = "http://127.0.0.1:8080/v1"
+
+// Client talks to an OpenAI-compatible server
+type Client struct {
+	// BaseURL is the API root including the version, e.g. http://127.0.0.1:8080/v1
+	BaseURL string
+
+	// APIKey is sent as a bearer token when it is not empty
+	APIKey string
+
+	// HTTPClient is the client used to issue requests
+	HTTPClient *http.Client
+}
+
+// Message is a single chat message
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRequest is the body of a /chat/completions call
+type ChatRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Stream      bool      `json:"stream"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
+	MaxTokens   *int      `json:"max_tokens,omitempty"`
+	Seed        *int      `json:"seed,omitempty"`
+	Stop        []string  `json:"stop,omitempty"`
+}
+
+// Choice is one completion of a chat request
+type Choice struct {
+	Index        int     `json:"index"`
+	Message      Message `json:"message"`
+	Delta        Message `json:"delta"`
+	FinishReason string  `json:"finish_reason,omitempty"`
+}
+
+// Usage reports the tokens a request consumed
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChatResponse is the body returned by /chat/completions, and each chunk of
+// a streamed response
+type ChatResponse struct {
+	ID      string   `json:"id"`
+	Model   string   `json:"model"`
+	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage,omitempty"`
+}
+
+// Text returns the content of the first choice
+func (r *ChatResponse) Text() string {
+	if len(r.Choices) == 0 {
+		return ""
+	}
+	return r.Choices[0].Message.Content
+}
+
+// ModelInfo is an entry returned by /models
+type ModelInfo struct {
+	ID      string `json:"id"`
+	OwnedBy string `json:"owned_by,omitempty"`
+	Created int64  `json:"created,omitempty"`
+}
+
+// ModelList is the body returned by /models
+type ModelList struct {
+	Data []ModelInfo `json:"data"`
+}
+
+// TokenFunc receives each chunk of text as the model produces it. Returning
+// an error aborts the stream.
+type TokenFunc func(token string) error
+
+// NewClient creates a client for the given base URL, falling back to the
+// local llama.cpp default. A base URL without a path gets /v1 appended.
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		BaseURL:    ResolveBaseURL(baseURL),
+		APIKey:     apiKey,
+		HTTPClient: &http.Client{},
+	}
+}
+
+// ResolveBaseURL normalizes the configured base URL
+func ResolveBaseURL(raw string) string {
+	raw = strings.TrimSuffix(strings.TrimSpace(raw), "/")
+	if raw == "" {
+		return DefaultBaseURL
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	if u, err := url.Parse(raw); err == nil && u.Path == "" {
+		raw += "/v1"
+	}
+	return raw
+}
+
+// Models returns the models the server serves. It doubles as a health check.
+func (c *Client) Models(ctx context.Context) (*ModelList, error) {
+	var resp ModelList
+	if err := c.do(ctx, http.MethodGet, "/models", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Chat runs a conversation through /chat/completions
+func (c *Client) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if req.Model == "" {
+		return nil, fmt.Errorf("model name is required")
+	}
+	req.Stream = false
+
+	var resp ChatResponse
+	if err := c.do(ctx, http.MethodPost, "/chat/completions", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ChatStream runs a conversation through /chat/completions with server-sent
+// events, calling fn for each token as it arrives. The returned response
+// carries the full text in its first choice's message.
+func (c *Client) ChatStream(ctx context.Context, req ChatRequest, fn TokenFunc) (*ChatResponse, error) {
+	if req.Model == "" {
+		return nil, fmt.Errorf("model name is required")
+	}
+	req.Stream = true
+
+	resp, err := c.send(ctx, http.MethodPost, "/chat/completions", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Some servers ignore "stream" and answer with a single JSON object
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		var whole ChatResponse
+		if err := json.NewDecoder(resp.Body).Decode(&whole); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %v", err)
+		}
+		if text := whole.Text(); text != "" && fn != nil {
+			if err := fn(text); err != nil {
+				return nil, err
+			}
+		}
+		return &whole, nil
+	}
+
+	var (
+		text  strings.Builder
+		final = ChatResponse{Model: req.Model}
+	)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), 8<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		if apiErr := inbandError([]byte(data), resp.StatusCode); apiErr != nil {
+			return nil, apiErr
+		}
+
+		var chunk ChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %v", err)
+		}
+		if chunk.ID != "" {
+			final.ID = chunk.ID
+		}
+		if chunk.Model != "" {
+			final.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			final.Usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			final.Choices = []Choice{{FinishReason: choice.FinishReason}}
+		}
+		token := choice.Delta.Content
+		if token == "" {
+			continue
+		}
+		text.WriteString(token)
+		if fn != nil {
+			if err := fn(token); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("failed to read stream: %v", err)
+	}
+
+	if len(final.Choices) == 0 {
+		final.Choices = []Choice{{}}
+	}
+	final.Choices[0].Message = Message{Role: "assistant", Content: text.String()}
+	return &final, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	resp, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
+
+// send issues the request and converts transport failures and non-2xx
+// responses into typed errors. The caller must close the response body.
+func (c *Client) send(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("%w at %s: %v", ErrServerUnreachable, c.BaseURL, unwrapURLError(err))
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if apiErr := inbandError(data, resp.StatusCode); apiErr != nil {
+			return nil, apiErr
+		}
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	return resp, nil
+}
+
+// inbandError decodes an error object, which servers send either as
+// {"error": {"message": ...}} or {"error": "..."}
+func inbandError(data []byte, status int) *APIError {
+	var payload struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil || len(payload.Error) == 0 || string(payload.Error) == "null" {
+		return nil
+	}
+
+	apiErr := &APIError{StatusCode: status}
+	var detail struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    any    `json:"code"`
+	}
+	if err := json.Unmarshal(payload.Error, &detail); err == nil {
+		apiErr.Message, apiErr.Type = detail.Message, detail.Type
+		if detail.Code != nil {
+			apiErr.Code = fmt.Sprint(detail.Code)
+		}
+	} else {
+		json.Unmarshal(payload.Error, &apiErr.Message)
+	}
+	return apiErr
+}
+
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
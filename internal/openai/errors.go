@@ -0,0 +1,67 @@
+package openai
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrModelNotFound is returned when the server does not serve the requested model
+	ErrModelNotFound = errors.New("model not found")
+
+	// ErrServerUnreachable is returned when the server cannot be contacted
+	ErrServerUnreachable = errors.New("server unreachable")
+
+	// ErrContextOverflow is returned when the prompt does not fit in the model context window
+	ErrContextOverflow = errors.New("prompt exceeds the model context length")
+
+	// ErrTemporary is returned when the server fails in a way that may pass,
+	// such as a 5xx status or too many requests
+	ErrTemporary = errors.New("temporary server error")
+
+	// ErrUnauthorized is returned when the API key is missing or rejected
+	ErrUnauthorized = errors.New("unauthorized, check the API key")
+)
+
+// APIError is an error response returned by an OpenAI-compatible server
+type APIError struct {
+	StatusCode int
+	Message    string
+	Type       string
+	Code       string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("API error: %s", http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("API error (%d): %s", e.StatusCode, e.Message)
+}
+
+// Unwrap maps the API error onto one of the package sentinel errors, if any applies
+func (e *APIError) Unwrap() error {
+	msg := strings.ToLower(e.Message)
+
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case e.Code == "model_not_found",
+		e.StatusCode == http.StatusNotFound && strings.Contains(msg, "model"),
+		strings.Contains(msg, "model") && strings.Contains(msg, "not found"),
+		strings.Contains(msg, "model") && strings.Contains(msg, "does not exist"):
+		return ErrModelNotFound
+	case e.Code == "context_length_exceeded",
+		strings.Contains(msg, "context length"),
+		strings.Contains(msg, "context window"),
+		strings.Contains(msg, "context size"),
+		strings.Contains(msg, "exceeds maximum"):
+		return ErrContextOverflow
+	case e.StatusCode >= http.StatusInternalServerError,
+		e.StatusCode == http.StatusTooManyRequests,
+		e.StatusCode == http.StatusRequestTimeout:
+		return ErrTemporary
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(server.URL+"/v1", "sk-test")
+}
+
+func TestResolveBaseURL(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "should use the local llama.cpp default", want: DefaultBaseURL},
+		{name: "should add /v1 to bare servers", raw: "http://gpu-box:8000", want: "http://gpu-box:8000/v1"},
+		{name: "should add a scheme to bare hosts", raw: "localhost:1234", want: "http://localhost:1234/v1"},
+		{name: "should keep explicit paths", raw: "https://llm.example.com/openai/v1/", want: "https://llm.example.com/openai/v1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveBaseURL(tt.raw); got != tt.want {
+				t.Errorf("ResolveBaseURL(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChat(t *testing.T) {
+	client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("Authorization = %q, want the API key", got)
+		}
+
+		var req ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if req.Stream {
+			t.Error("Chat() should disable streaming")
+		}
+		last := req.Messages[len(req.Messages)-1]
+		json.NewEncoder(w).Encode(ChatResponse{
+			Model:   req.Model,
+			Choices: []Choice{{Message: Message{Role: "assistant", Content: strings.ToUpper(last.Content)}}},
+			Usage:   &Usage{PromptTokens: 3, CompletionTokens: 1},
+		})
+	})
+
+	resp, err := client.Chat(context.Background(), ChatRequest{
+		Model:    "qwen2.5-coder",
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if resp.Text() != "HI" || resp.Usage.PromptTokens != 3 {
+		t.Errorf("Chat() = %+v, want HI with usage", resp)
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	tests := []struct {
+		name       string
+		events     []string
+		wantTokens []string
+		wantText   string
+		wantErr    bool
+	}{
+		{
+			name: "should deliver tokens in order",
+			events: []string{
+				`{"choices":[{"delta":{"role":"assistant"}}]}`,
+				`{"choices":[{"delta":{"content":"feat: "}}]}`,
+				`{"choices":[{"delta":{"content":"add login"},"finish_reason":"stop"}]}`,
+				`[DONE]`,
+			},
+			wantTokens: []string{"feat: ", "add login"},
+			wantText:   "feat: add login",
+		},
+		{
+			name: "should surface in-band errors",
+			events: []string{
+				`{"choices":[{"delta":{"content":"feat"}}]}`,
+				`{"error":{"message":"model crashed","type":"server_error"}}`,
+			},
+			wantTokens: []string{"feat"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				var req ChatRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				if !req.Stream {
+					t.Error("ChatStream() should request streaming")
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, event := range tt.events {
+					fmt.Fprintf(w, "data: %s\n\n", event)
+				}
+			})
+
+			var tokens []string
+			resp, err := client.ChatStream(context.Background(), ChatRequest{Model: "m"}, func(token string) error {
+				tokens = append(tokens, token)
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ChatStream() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(tokens, "|") != strings.Join(tt.wantTokens, "|") {
+				t.Errorf("ChatStream() tokens = %q, want %q", tokens, tt.wantTokens)
+			}
+			if !tt.wantErr && resp.Text() != tt.wantText {
+				t.Errorf("ChatStream() text = %q, want %q", resp.Text(), tt.wantText)
+			}
+		})
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+	}{
+		{
+			name:    "should report missing model",
+			status:  http.StatusNotFound,
+			body:    `{"error":{"message":"The model 'nope' does not exist","code":"model_not_found"}}`,
+			wantErr: ErrModelNotFound,
+		},
+		{
+			name:    "should report context overflow",
+			status:  http.StatusBadRequest,
+			body:    `{"error":{"message":"the request exceeds the available context size","type":"invalid_request_error"}}`,
+			wantErr: ErrContextOverflow,
+		},
+		{
+			name:    "should report rejected keys",
+			status:  http.StatusUnauthorized,
+			body:    `{"error":"invalid api key"}`,
+			wantErr: ErrUnauthorized,
+		},
+		{
+			name:    "should report rate limits as temporary",
+			status:  http.StatusTooManyRequests,
+			body:    `{"error":{"message":"rate limit reached","type":"requests"}}`,
+			wantErr: ErrTemporary,
+		},
+		{
+			name:   "should report other API errors",
+			status: http.StatusBadRequest,
+			body:   `bad request`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			_, err := client.Chat(context.Background(), ChatRequest{Model: "nope"})
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Chat() error = %v, want *APIError", err)
+			}
+			if apiErr.StatusCode != tt.status {
+				t.Errorf("APIError.StatusCode = %d, want %d", apiErr.StatusCode, tt.status)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Chat() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	baseURL := server.URL
+	server.Close()
+
+	if _, err := NewClient(baseURL, "").Models(context.Background()); !errors.Is(err, ErrServerUnreachable) {
+		t.Errorf("Models() error = %v, want %v", err, ErrServerUnreachable)
+	}
+}
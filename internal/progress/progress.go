@@ -0,0 +1,85 @@
+// Package progress draws download progress bars. Like the spinner they are
+// drawn on stderr, leaving stdout to the command's output.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dakoctba/cmt/internal/ui"
+)
+
+// width is the number of cells of the bar
+const width = 30
+
+// Bar shows the progress of a download made of steps, such as the layers
+// of a model, on a single line
+type Bar struct {
+	out   io.Writer
+	label string
+
+	// last is the line drawn last, to skip redrawing the same one
+	last  string
+	drawn bool
+
+	// plain is set when stderr is not a terminal, e.g. in CI logs: each
+	// step's status is printed on a line of its own, without the bar
+	plain bool
+}
+
+// New creates a bar introduced by label, e.g. "Pulling llama3.1"
+func New(label string) *Bar {
+	return &Bar{out: os.Stderr, label: label, plain: !ui.IsTerminal(os.Stderr)}
+}
+
+// Update draws the progress of the current step: completed of total bytes,
+// or only the step's status when total is zero
+func (b *Bar) Update(status string, completed, total int64) {
+	line := fmt.Sprintf("⬇ %s: %s", b.label, status)
+	if b.plain {
+		if line != b.last {
+			b.last = line
+			fmt.Fprintln(b.out, line)
+		}
+		return
+	}
+	if total > 0 {
+		if completed > total {
+			completed = total
+		}
+		filled := int(completed * width / total)
+		line = fmt.Sprintf("⬇ %s [%s%s] %3d%% %s/%s",
+			b.label,
+			strings.Repeat("█", filled), strings.Repeat("░", width-filled),
+			completed*100/total, Bytes(completed), Bytes(total))
+	}
+	if line == b.last {
+		return
+	}
+	b.last, b.drawn = line, true
+	fmt.Fprintf(b.out, "\r\033[K%s", line)
+}
+
+// Finish ends the bar's line, keeping the last progress drawn visible
+func (b *Bar) Finish() {
+	if b.drawn {
+		fmt.Fprintln(b.out)
+		b.drawn = false
+	}
+}
+
+// Bytes formats a byte count with a decimal unit, e.g. "4.7 GB"
+func Bytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
@@ -0,0 +1,132 @@
+// Package prompt holds the instructions sent to the model.
+package prompt
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/dakoctba/cmt/internal/message"
+	"github.com/dakoctba/cmt/internal/style"
+)
+
+// DefaultTemplate is the built-in commit prompt, a text/template rendered
+// with Data
+//
+//go:embed commit.tmpl
+var DefaultTemplate string
+
+// DefaultLanguage is the language messages are written in when none is
+// configured
+const DefaultLanguage = "English"
+
+// Data is what a commit prompt template is rendered with
+type Data struct {
+	// Diff is the staged diff, or its summary when it is too large
+	Diff string
+
+	// Files are the paths of the staged files
+	Files []string
+
+	// Branch is the checked-out branch, empty when HEAD is detached
+	Branch string
+
+	// Ticket is the ticket ID read from the branch name; TypeHint is the
+	// commit type the branch name suggests
+	Ticket   string
+	TypeHint string
+
+	// RecentCommits are the subjects of the latest commits, newest first
+	RecentCommits []string
+
+	// Types are the commit types the repository's rules allow
+	Types []string
+
+	// Scopes are the scopes of the changed files, the one covering most
+	// files first; Scope is the scope the message should use, empty when
+	// it should be left out
+	Scopes []string
+	Scope  string
+
+	// Language is the language the message should be written in
+	Language string
+
+	// Style is the commit style learned from the repository's history
+	Style style.Style
+}
+
+// typeDescriptions explain the Conventional Commits types
+var typeDescriptions = map[string]string{
+	"feat":     "A new feature",
+	"fix":      "A bug fix",
+	"docs":     "Documentation-only changes",
+	"style":    "Code style changes (formatting, missing semicolons, etc.)",
+	"refactor": "Code change that neither fixes a bug nor adds a feature",
+	"perf":     "Performance improvements",
+	"test":     "Adding or updating tests",
+	"build":    "Changes to the build system or external dependencies",
+	"ci":       "Changes to the CI configuration",
+	"chore":    "Routine tasks (build process, dependencies, etc.)",
+	"revert":   "Reverts a previous commit",
+}
+
+// funcs are the functions available to templates besides the text/template
+// builtins
+var funcs = template.FuncMap{
+	// describe returns the description of a commit type, if it is known
+	"describe": func(commitType string) string {
+		return typeDescriptions[commitType]
+	},
+	"join": strings.Join,
+}
+
+// Parse checks a commit prompt template, returning it ready to render. An
+// empty text selects DefaultTemplate.
+func Parse(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultTemplate
+	}
+	tmpl, err := template.New("prompt").Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Render renders a commit prompt template, DefaultTemplate when text is empty
+func Render(text string, data Data) (string, error) {
+	tmpl, err := Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// Commit asks the model for a commit message describing diff, using the
+// built-in template
+func Commit(diff string) (string, error) {
+	return Render("", Data{Diff: diff, Types: message.CommonTypes, Language: DefaultLanguage})
+}
+
+// Summary asks for a short summary of part of one file's diff
+func Summary(path, patch string) string {
+	return fmt.Sprintf(`You are given part of a Git diff for the file %s. Summarize what was changed and, if it is apparent, why, in one or two short sentences. Do not include any additional text.
+
+%s`, path, patch)
+}
+
+// Revision asks the model to fix a commit message given feedback on what
+// was wrong with it
+func Revision(feedback string) string {
+	return fmt.Sprintf(`Your commit message does not follow the Conventional Commits rules:
+
+%s
+
+Fix these problems and answer again in the same format. Do not include any additional text or explanations.`, feedback)
+}
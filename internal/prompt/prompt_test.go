@@ -0,0 +1,68 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	data := Data{
+		Diff:          "+package main",
+		Files:         []string{"main.go", "go.mod"},
+		Branch:        "feature/login",
+		RecentCommits: []string{"feat(api): add users endpoint"},
+		Types:         []string{"feat", "fix", "security"},
+		Language:      "German",
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "should render the built-in template",
+			want: []string{"\tfeat: A new feature\n", "\tsecurity\n", "Write the title and description in German.", "instruction.\n+package main"},
+		},
+		{
+			name: "should render every variable",
+			text: "{{.Branch}} {{join .Files \",\"}} {{range .RecentCommits}}{{.}}{{end}} {{describe \"fix\"}}",
+			want: []string{"feature/login main.go,go.mod feat(api): add users endpoint A bug fix"},
+		},
+		{
+			name:    "should reject invalid templates",
+			text:    "{{.Diff",
+			wantErr: true,
+		},
+		{
+			name:    "should reject unknown variables",
+			text:    "{{.Author}}",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.text, data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Render() = %q, want it to contain %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCommit(t *testing.T) {
+	got, err := Commit("+func Login() {}")
+	if err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if !strings.Contains(got, "+func Login() {}") {
+		t.Errorf("Commit() = %q, want it to contain the diff", got)
+	}
+}
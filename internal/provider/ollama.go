@@ -0,0 +1,146 @@
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/dakoctba/cmt/internal/ollama"
+)
+
+// ollamaProvider talks to an Ollama server through its native API
+type ollamaProvider struct {
+	client *ollama.Client
+}
+
+func newOllama(baseURL string) *ollamaProvider {
+	return &ollamaProvider{client: ollama.NewClient(baseURL)}
+}
+
+// ollamaErrors maps the client's errors to the sentinels, the most specific
+// first
+var ollamaErrors = []errorMapping{
+	{ollama.ErrModelNotFound, ErrModelNotFound},
+	{ollama.ErrServerUnreachable, ErrUnreachable},
+	{ollama.ErrContextOverflow, ErrContextOverflow},
+	{ollama.ErrTemporary, ErrTemporary},
+}
+
+func (p *ollamaProvider) Name() string    { return Ollama }
+func (p *ollamaProvider) BaseURL() string { return p.client.BaseURL }
+
+func (p *ollamaProvider) Health(ctx context.Context) error {
+	return classify(p.client.CheckInstallation(ctx), ollamaErrors)
+}
+
+func (p *ollamaProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	resp, err := p.client.Chat(ctx, p.chatRequest(req))
+	if err != nil {
+		return nil, classify(err, ollamaErrors)
+	}
+	return ollamaResponse(resp), nil
+}
+
+func (p *ollamaProvider) Stream(ctx context.Context, req Request, fn TokenFunc) (*Response, error) {
+	resp, err := p.client.ChatStream(ctx, p.chatRequest(req), ollama.TokenFunc(fn))
+	if err != nil {
+		return nil, classify(err, ollamaErrors)
+	}
+	return ollamaResponse(resp), nil
+}
+
+func (p *ollamaProvider) ListModels(ctx context.Context) ([]Model, error) {
+	resp, err := p.client.List(ctx)
+	if err != nil {
+		return nil, classify(err, ollamaErrors)
+	}
+
+	models := make([]Model, 0, len(resp.Models))
+	for _, m := range resp.Models {
+		models = append(models, Model{
+			Name:          m.Name,
+			Size:          m.Size,
+			Family:        m.Details.Family,
+			ParameterSize: m.Details.ParameterSize,
+			Quantization:  m.Details.QuantizationLevel,
+		})
+	}
+	return models, nil
+}
+
+func (p *ollamaProvider) ShowModel(ctx context.Context, name string) (Model, error) {
+	resp, err := p.client.Show(ctx, ollama.ShowRequest{Model: name})
+	if err != nil {
+		return Model{}, classify(err, ollamaErrors)
+	}
+	return Model{
+		Name:          name,
+		Family:        resp.Details.Family,
+		ParameterSize: resp.Details.ParameterSize,
+		Quantization:  resp.Details.QuantizationLevel,
+		ContextLength: resp.ContextLength(),
+	}, nil
+}
+
+func (p *ollamaProvider) Pull(ctx context.Context, name string, fn ProgressFunc) error {
+	err := p.client.Pull(ctx, ollama.PullRequest{Model: name}, func(update ollama.PullResponse) error {
+		if fn == nil {
+			return nil
+		}
+		return fn(Progress{Status: update.Status, Total: update.Total, Completed: update.Completed})
+	})
+	return classify(err, ollamaErrors)
+}
+
+func (p *ollamaProvider) chatRequest(req Request) ollama.ChatRequest {
+	messages := make([]ollama.Message, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = ollama.Message{Role: m.Role, Content: m.Content}
+	}
+	return ollama.ChatRequest{
+		Model:     req.Model,
+		Messages:  messages,
+		Options:   ollamaOptions(req.Options),
+		KeepAlive: req.Options.KeepAlive,
+	}
+}
+
+// ollamaOptions converts the generation options to Ollama's "options"
+// object
+func ollamaOptions(o Options) map[string]any {
+	options := map[string]any{}
+	if o.Temperature != nil {
+		options["temperature"] = *o.Temperature
+	}
+	if o.TopP != nil {
+		options["top_p"] = *o.TopP
+	}
+	if o.TopK != nil {
+		options["top_k"] = *o.TopK
+	}
+	if o.Seed != nil {
+		options["seed"] = *o.Seed
+	}
+	if o.NumCtx > 0 {
+		options["num_ctx"] = o.NumCtx
+	}
+	if o.NumPredict > 0 {
+		options["num_predict"] = o.NumPredict
+	}
+	if len(o.Stop) > 0 {
+		options["stop"] = o.Stop
+	}
+	if len(options) == 0 {
+		return nil
+	}
+	return options
+}
+
+func ollamaResponse(resp *ollama.ChatResponse) *Response {
+	return &Response{
+		Model:            resp.Model,
+		Text:             resp.Message.Content,
+		PromptTokens:     resp.PromptEvalCount,
+		CompletionTokens: resp.EvalCount,
+		Duration:         time.Duration(resp.TotalDuration),
+	}
+}
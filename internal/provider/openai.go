@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dakoctba/cmt/internal/openai"
+)
+
+// openAIProvider talks to a server speaking the OpenAI-compatible chat
+// completions protocol (llama.cpp server, vLLM, LM Studio, LocalAI, ...)
+type openAIProvider struct {
+	client *openai.Client
+}
+
+func newOpenAI(baseURL, apiKey string) *openAIProvider {
+	return &openAIProvider{client: openai.NewClient(baseURL, apiKey)}
+}
+
+// openAIErrors are checked in order, see classify
+var openAIErrors = []errorMapping{
+	{openai.ErrModelNotFound, ErrModelNotFound},
+	{openai.ErrServerUnreachable, ErrUnreachable},
+	{openai.ErrContextOverflow, ErrContextOverflow},
+	{openai.ErrTemporary, ErrTemporary},
+}
+
+func (p *openAIProvider) Name() string    { return OpenAI }
+func (p *openAIProvider) BaseURL() string { return p.client.BaseURL }
+
+func (p *openAIProvider) Health(ctx context.Context) error {
+	if _, err := p.client.Models(ctx); err != nil {
+		return classify(fmt.Errorf("no OpenAI-compatible server is reachable at %s. Please start it or check base_url: %w", p.client.BaseURL, err), openAIErrors)
+	}
+	return nil
+}
+
+func (p *openAIProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	start := time.Now()
+	resp, err := p.client.Chat(ctx, p.chatRequest(req))
+	if err != nil {
+		return nil, classify(err, openAIErrors)
+	}
+	return openAIResponse(resp, time.Since(start)), nil
+}
+
+func (p *openAIProvider) Stream(ctx context.Context, req Request, fn TokenFunc) (*Response, error) {
+	start := time.Now()
+	resp, err := p.client.ChatStream(ctx, p.chatRequest(req), openai.TokenFunc(fn))
+	if err != nil {
+		return nil, classify(err, openAIErrors)
+	}
+	return openAIResponse(resp, time.Since(start)), nil
+}
+
+func (p *openAIProvider) ListModels(ctx context.Context) ([]Model, error) {
+	resp, err := p.client.Models(ctx)
+	if err != nil {
+		return nil, classify(err, openAIErrors)
+	}
+
+	models := make([]Model, 0, len(resp.Data))
+	for _, m := range resp.Data {
+		models = append(models, Model{Name: m.ID})
+	}
+	return models, nil
+}
+
+func (p *openAIProvider) chatRequest(req Request) openai.ChatRequest {
+	messages := make([]openai.Message, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = openai.Message{Role: m.Role, Content: m.Content}
+	}
+	chat := openai.ChatRequest{
+		Model:       req.Model,
+		Messages:    messages,
+		Temperature: req.Options.Temperature,
+		TopP:        req.Options.TopP,
+		Seed:        req.Options.Seed,
+		Stop:        req.Options.Stop,
+	}
+	if req.Options.NumPredict > 0 {
+		chat.MaxTokens = &req.Options.NumPredict
+	}
+	return chat
+}
+
+func openAIResponse(resp *openai.ChatResponse, elapsed time.Duration) *Response {
+	r := &Response{Model: resp.Model, Text: resp.Text(), Duration: elapsed}
+	if resp.Usage != nil {
+		r.PromptTokens = resp.Usage.PromptTokens
+		r.CompletionTokens = resp.Usage.CompletionTokens
+	}
+	return r
+}
@@ -0,0 +1,222 @@
+// Package provider abstracts the model server cmt talks to, so that commit
+// message generation does not depend on a particular API.
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Provider names accepted in the configuration
+const (
+	Ollama = "ollama"
+	OpenAI = "openai"
+)
+
+// Names lists the supported providers
+var Names = []string{Ollama, OpenAI}
+
+var (
+	// ErrModelNotFound is returned when the server does not have the requested model
+	ErrModelNotFound = errors.New("model not found")
+
+	// ErrUnreachable is returned when the server cannot be contacted
+	ErrUnreachable = errors.New("server unreachable")
+
+	// ErrContextOverflow is returned when the prompt does not fit in the model context window
+	ErrContextOverflow = errors.New("prompt exceeds the model context length")
+
+	// ErrTemporary is returned when the server fails in a way that may pass
+	// if the request is repeated, e.g. while it loads the model
+	ErrTemporary = errors.New("temporary server error")
+)
+
+// Provider is a model server
+type Provider interface {
+	// Name returns the provider name, e.g. "ollama"
+	Name() string
+
+	// BaseURL returns the address of the server
+	BaseURL() string
+
+	// Health checks that the server is reachable
+	Health(ctx context.Context) error
+
+	// Generate runs the conversation and returns the complete answer
+	Generate(ctx context.Context, req Request) (*Response, error)
+
+	// Stream runs the conversation, calling fn for each token as it arrives
+	Stream(ctx context.Context, req Request, fn TokenFunc) (*Response, error)
+
+	// ListModels returns the models the server can run
+	ListModels(ctx context.Context) ([]Model, error)
+}
+
+// TokenFunc receives each chunk of text as the model produces it. Returning
+// an error aborts the stream.
+type TokenFunc func(token string) error
+
+// Message is a single chat message
+type Message struct {
+	Role    string
+	Content string
+}
+
+// Request is a conversation to complete
+type Request struct {
+	Model    string
+	Messages []Message
+	Options  Options
+}
+
+// Options tune how the model generates its answer. Nil and zero fields
+// leave the server's default, and providers ignore the options their API
+// lacks.
+type Options struct {
+	Temperature *float64
+	TopP        *float64
+	TopK        *int
+	Seed        *int
+
+	// NumCtx is the context window and NumPredict the most tokens
+	// generated
+	NumCtx     int
+	NumPredict int
+
+	// Stop are sequences that end generation
+	Stop []string
+
+	// KeepAlive is how long the server keeps the model loaded after the
+	// request, e.g. "10m"
+	KeepAlive string
+}
+
+// Response is the model's answer
+type Response struct {
+	Model string
+	Text  string
+
+	// PromptTokens and CompletionTokens are zero when the server does not
+	// report them
+	PromptTokens     int
+	CompletionTokens int
+	Duration         time.Duration
+}
+
+// Model is a model available on the server
+type Model struct {
+	Name string
+
+	// Size, Family, ParameterSize and Quantization are only known for some
+	// providers
+	Size          int64
+	Family        string
+	ParameterSize string
+	Quantization  string
+
+	// ContextLength is the longest context the model supports, only known
+	// from Manager.ShowModel
+	ContextLength int
+}
+
+// Manager is implemented by providers whose server downloads and manages
+// its models, as Ollama does
+type Manager interface {
+	// ShowModel returns the details of a model on the server, its context
+	// length included
+	ShowModel(ctx context.Context, name string) (Model, error)
+
+	// Pull downloads a model, calling fn with the progress
+	Pull(ctx context.Context, name string, fn ProgressFunc) error
+}
+
+// Progress is a step of a model download
+type Progress struct {
+	// Status describes the step, e.g. "pulling manifest" or "success"
+	Status string
+
+	// Completed of Total bytes of the current layer are downloaded; both
+	// are zero for steps that download nothing
+	Total     int64
+	Completed int64
+}
+
+// ProgressFunc receives the progress of a download. Returning an error
+// aborts it.
+type ProgressFunc func(Progress) error
+
+// New creates the named provider. An empty name selects Ollama, and an
+// empty base URL the provider's default address.
+func New(name, baseURL, apiKey string) (Provider, error) {
+	switch name {
+	case "", Ollama:
+		return newOllama(baseURL), nil
+	case OpenAI:
+		return newOpenAI(baseURL, apiKey), nil
+	}
+	return nil, fmt.Errorf("unknown provider %q, expected one of %v", name, Names)
+}
+
+// FindModel looks a model up by name. As with Ollama, a name without a tag
+// stands for its "latest" tag.
+func FindModel(models []Model, name string) (Model, bool) {
+	for _, m := range models {
+		if m.Name == name || !strings.Contains(name, ":") && m.Name == name+":latest" {
+			return m, true
+		}
+	}
+	return Model{}, false
+}
+
+// Complete runs req, streaming tokens to fn when it is not nil
+func Complete(ctx context.Context, p Provider, req Request, fn TokenFunc) (*Response, error) {
+	if fn == nil {
+		return p.Generate(ctx, req)
+	}
+	return p.Stream(ctx, req, fn)
+}
+
+// Prompt is a single user message request
+func Prompt(model, text string) Request {
+	return Request{Model: model, Messages: []Message{{Role: "user", Content: text}}}
+}
+
+// Transient reports whether a failed request is worth repeating: the
+// server could not be reached or answered with a temporary error
+func Transient(err error) bool {
+	return errors.Is(err, ErrTemporary) || errors.Is(err, ErrUnreachable)
+}
+
+// classified is an error that also matches one of the package sentinel
+// errors, keeping the provider's original message
+type classified struct {
+	err      error
+	sentinel error
+}
+
+func (e *classified) Error() string   { return e.err.Error() }
+func (e *classified) Unwrap() []error { return []error{e.err, e.sentinel} }
+
+// errorMapping pairs a provider-specific error with the sentinel it stands
+// for
+type errorMapping struct {
+	specific error
+	sentinel error
+}
+
+// classify makes err match the first sentinel whose provider-specific
+// counterpart it matches
+func classify(err error, mappings []errorMapping) error {
+	if err == nil {
+		return nil
+	}
+	for _, m := range mappings {
+		if errors.Is(err, m.specific) {
+			return &classified{err: err, sentinel: m.sentinel}
+		}
+	}
+	return err
+}
@@ -0,0 +1,273 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dakoctba/cmt/internal/ollama/ollamatest"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		baseURL  string
+		wantName string
+		wantURL  string
+		wantErr  bool
+	}{
+		{name: "should default to ollama", wantName: Ollama, baseURL: "gpu-box", wantURL: "http://gpu-box:11434"},
+		{name: "should create an OpenAI-compatible provider", provider: OpenAI, baseURL: "http://127.0.0.1:1234", wantName: OpenAI, wantURL: "http://127.0.0.1:1234/v1"},
+		{name: "should reject unknown providers", provider: "bard", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.provider, tt.baseURL, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if p.Name() != tt.wantName || p.BaseURL() != tt.wantURL {
+				t.Errorf("New() = %s at %s, want %s at %s", p.Name(), p.BaseURL(), tt.wantName, tt.wantURL)
+			}
+		})
+	}
+}
+
+// openAIServer answers chat completions with the upper-cased prompt and
+// serves a single model
+func openAIServer(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models":
+			w.Write([]byte(`{"data":[{"id":"qwen2.5-coder"}]}`))
+		case "/v1/chat/completions":
+			var req struct {
+				Model    string    `json:"model"`
+				Messages []Message `json:"messages"`
+				Stream   bool      `json:"stream"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Model != "qwen2.5-coder" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":{"message":"model not found","code":"model_not_found"}}`))
+				return
+			}
+			answer := strings.ToUpper(req.Messages[0].Content)
+			if req.Stream {
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, token := range []string{answer[:2], answer[2:]} {
+					data, _ := json.Marshal(map[string]any{"choices": []map[string]any{{"delta": map[string]string{"content": token}}}})
+					w.Write([]byte("data: " + string(data) + "\n\n"))
+				}
+				w.Write([]byte("data: [DONE]\n\n"))
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{
+				"choices": []map[string]any{{"message": map[string]string{"content": answer}}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestProviders(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider func(t *testing.T) Provider
+		model    string
+	}{
+		{
+			name: "should talk to ollama",
+			provider: func(t *testing.T) Provider {
+				server := ollamatest.NewServer(t)
+				server.Response = "HELLO"
+				server.Models = []string{"llama3.1"}
+				p, _ := New(Ollama, server.URL, "")
+				return p
+			},
+			model: "llama3.1",
+		},
+		{
+			name: "should talk to OpenAI-compatible servers",
+			provider: func(t *testing.T) Provider {
+				p, _ := New(OpenAI, openAIServer(t), "")
+				return p
+			},
+			model: "qwen2.5-coder",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			p := tt.provider(t)
+
+			if err := p.Health(ctx); err != nil {
+				t.Fatalf("Health() error = %v", err)
+			}
+
+			models, err := p.ListModels(ctx)
+			if err != nil || len(models) != 1 || models[0].Name != tt.model {
+				t.Errorf("ListModels() = %v, %v, want [%s]", models, err, tt.model)
+			}
+
+			resp, err := p.Generate(ctx, Prompt(tt.model, "hello"))
+			if err != nil || resp.Text != "HELLO" {
+				t.Errorf("Generate() = %v, %v, want HELLO", resp, err)
+			}
+
+			var tokens []string
+			resp, err = p.Stream(ctx, Prompt(tt.model, "hello"), func(token string) error {
+				tokens = append(tokens, token)
+				return nil
+			})
+			if err != nil || resp.Text != "HELLO" || strings.Join(tokens, "") != "HELLO" {
+				t.Errorf("Stream() = %v, %v with tokens %q, want HELLO", resp, err, tokens)
+			}
+
+			if _, err := p.Generate(ctx, Prompt("missing", "hello")); !errors.Is(err, ErrModelNotFound) {
+				t.Errorf("Generate() error = %v, want %v", err, ErrModelNotFound)
+			}
+		})
+	}
+}
+
+func TestHealthUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	baseURL := server.URL
+	server.Close()
+
+	for _, name := range Names {
+		p, _ := New(name, baseURL, "")
+		if err := p.Health(context.Background()); !errors.Is(err, ErrUnreachable) {
+			t.Errorf("%s Health() error = %v, want %v", name, err, ErrUnreachable)
+		}
+	}
+}
+
+func TestRequestOptions(t *testing.T) {
+	temperature, seed := 0.2, 42
+	options := Options{Temperature: &temperature, Seed: &seed, NumCtx: 8192, NumPredict: 200, Stop: []string{"END"}, KeepAlive: "10m"}
+
+	tests := []struct {
+		name  string
+		check func(t *testing.T)
+	}{
+		{
+			name: "should pass options to ollama",
+			check: func(t *testing.T) {
+				req := newOllama("").chatRequest(Request{Model: "llama3.1", Options: options})
+				want := map[string]any{"temperature": 0.2, "seed": 42, "num_ctx": 8192, "num_predict": 200, "stop": []string{"END"}}
+				if fmt.Sprint(req.Options) != fmt.Sprint(want) || req.KeepAlive != "10m" {
+					t.Errorf("chatRequest() options = %v, keep_alive %q, want %v, 10m", req.Options, req.KeepAlive, want)
+				}
+			},
+		},
+		{
+			name: "should leave ollama's defaults alone",
+			check: func(t *testing.T) {
+				if req := newOllama("").chatRequest(Request{Model: "llama3.1"}); req.Options != nil {
+					t.Errorf("chatRequest() options = %v, want none", req.Options)
+				}
+			},
+		},
+		{
+			name: "should pass the options OpenAI-compatible servers know",
+			check: func(t *testing.T) {
+				req := newOpenAI("", "").chatRequest(Request{Model: "qwen2.5-coder", Options: options})
+				if *req.Temperature != 0.2 || *req.Seed != 42 || *req.MaxTokens != 200 || req.TopP != nil || len(req.Stop) != 1 {
+					t.Errorf("chatRequest() = %+v", req)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, tt.check)
+	}
+}
+
+func TestFindModel(t *testing.T) {
+	models := []Model{{Name: "llama3.1:latest"}, {Name: "qwen2.5-coder:14b"}, {Name: "gpt-4o-mini"}}
+
+	tests := []struct {
+		name  string
+		model string
+		want  bool
+	}{
+		{name: "should match the full name", model: "qwen2.5-coder:14b", want: true},
+		{name: "should match the latest tag without one", model: "llama3.1", want: true},
+		{name: "should match names without tags", model: "gpt-4o-mini", want: true},
+		{name: "should not match other tags", model: "qwen2.5-coder", want: false},
+		{name: "should not match other models", model: "phi3", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := FindModel(models, tt.model); got != tt.want {
+				t.Errorf("FindModel(%q) = %v, want %v", tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	var (
+		errFirst  = errors.New("first")
+		errSecond = errors.New("second")
+		mappings  = []errorMapping{{errFirst, ErrContextOverflow}, {errSecond, ErrTemporary}}
+	)
+	tests := []struct {
+		name     string
+		err      error
+		want     error
+		unwanted error
+	}{
+		{
+			name: "should match the sentinel of a specific error",
+			err:  fmt.Errorf("request failed: %w", errSecond),
+			want: ErrTemporary,
+		},
+		{
+			name:     "should match the first sentinel of an error matching several",
+			err:      errors.Join(errSecond, errFirst),
+			want:     ErrContextOverflow,
+			unwanted: ErrTemporary,
+		},
+		{
+			name:     "should leave other errors alone",
+			err:      errors.New("bad request"),
+			unwanted: ErrTemporary,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Repeat to catch an order that changes from run to run
+			for i := 0; i < 20; i++ {
+				err := classify(tt.err, mappings)
+				if tt.want != nil && !errors.Is(err, tt.want) {
+					t.Fatalf("classify() = %v, want it to match %v", err, tt.want)
+				}
+				if tt.unwanted != nil && errors.Is(err, tt.unwanted) {
+					t.Fatalf("classify() = %v, should not match %v", err, tt.unwanted)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+// Package redact masks secrets and personal data in diffs before they are
+// sent to a model.
+package redact
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Detector finds one kind of sensitive data
+type Detector struct {
+	// Name identifies the detector in placeholders and reports
+	Name string
+
+	// Pattern matches the sensitive data. When it has a group named
+	// "secret", only that group is replaced.
+	Pattern *regexp.Regexp
+
+	// Secret marks credentials, as opposed to personal data such as email
+	// addresses; only secrets make cmt refuse to run when blocking is on
+	Secret bool
+
+	// Accept, when set, filters the matches
+	Accept func(match string) bool
+}
+
+// Detectors returns the built-in detectors
+func Detectors() []Detector {
+	return []Detector{
+		{
+			Name:    "private-key",
+			Pattern: regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----(?:[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----)?`),
+			Secret:  true,
+		},
+		{
+			Name:    "aws-access-key",
+			Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[0-9A-Z]{16}\b`),
+			Secret:  true,
+		},
+		{
+			Name:    "aws-secret-key",
+			Pattern: regexp.MustCompile(`(?i)aws.{0,20}(?:secret|key).{0,20}?['"=:\s](?P<secret>[A-Za-z0-9/+=]{40})\b`),
+			Secret:  true,
+		},
+		{
+			Name:    "jwt",
+			Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{10,}`),
+			Secret:  true,
+		},
+		{
+			Name:    "credential",
+			Pattern: regexp.MustCompile(`(?i)(?:api[_-]?key|secret|token|passw(?:or)?d|pwd)["']?\s*[:=]\s*["']?(?P<secret>[^\s"'$<>{}]{8,})`),
+			Secret:  true,
+			Accept:  func(s string) bool { return entropy(s) >= 3 },
+		},
+		{
+			Name:    "high-entropy",
+			Pattern: regexp.MustCompile(`[A-Za-z0-9+/=_-]{20,}`),
+			Secret:  true,
+			Accept:  highEntropy,
+		},
+		{
+			Name:    "email",
+			Pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`),
+		},
+	}
+}
+
+// highEntropy reports whether s looks like a random token: mixing upper
+// and lower case letters and digits, with close to base64 entropy. Hex
+// digests such as commit hashes are lower case and never match.
+func highEntropy(s string) bool {
+	if !strings.ContainsAny(s, "0123456789") || strings.ToLower(s) == s || strings.ToUpper(s) == s {
+		return false
+	}
+	return entropy(s) >= 4.2
+}
+
+// entropy returns the Shannon entropy of s in bits per character
+func entropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+	var h float64
+	n := float64(len([]rune(s)))
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// Finding is one redacted value
+type Finding struct {
+	// Path is the file the value was first found in
+	Path        string
+	Detector    string
+	Secret      bool
+	Placeholder string
+	// Preview shows the first and last characters of the value
+	Preview string
+}
+
+// Redactor replaces sensitive data with placeholders. The same value is
+// always replaced by the same placeholder, so the model can still tell
+// that two changes refer to the same secret.
+type Redactor struct {
+	detectors    []Detector
+	placeholders map[string]string
+	counts       map[string]int
+	findings     []Finding
+}
+
+// New returns a redactor using the built-in detectors followed by the
+// given name → regular expression patterns
+func New(patterns map[string]string) (*Redactor, error) {
+	detectors := Detectors()
+
+	names := make([]string, 0, len(patterns))
+	for name := range patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		re, err := regexp.Compile(patterns[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %v", name, err)
+		}
+		detectors = append(detectors, Detector{Name: name, Pattern: re, Secret: true})
+	}
+
+	return &Redactor{
+		detectors:    detectors,
+		placeholders: map[string]string{},
+		counts:       map[string]int{},
+	}, nil
+}
+
+// Redact returns text, taken from the file at path, with every detected
+// value replaced by a placeholder such as [REDACTED_AWS_ACCESS_KEY_1]
+func (r *Redactor) Redact(path, text string) string {
+	for _, d := range r.detectors {
+		text = r.apply(d, path, text)
+	}
+	return text
+}
+
+func (r *Redactor) apply(d Detector, path, text string) string {
+	group := d.Pattern.SubexpIndex("secret")
+
+	var b strings.Builder
+	last := 0
+	for _, m := range d.Pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[0], m[1]
+		if group > 0 {
+			start, end = m[2*group], m[2*group+1]
+			if start < 0 {
+				continue
+			}
+		}
+		value := text[start:end]
+		if strings.HasPrefix(value, "[REDACTED_") || d.Accept != nil && !d.Accept(value) {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(r.placeholder(d, path, value))
+		last = end
+	}
+	if last == 0 {
+		return text
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func (r *Redactor) placeholder(d Detector, path, value string) string {
+	if p, ok := r.placeholders[value]; ok {
+		return p
+	}
+	r.counts[d.Name]++
+	name := strings.ToUpper(strings.NewReplacer("-", "_", " ", "_").Replace(d.Name))
+	p := fmt.Sprintf("[REDACTED_%s_%d]", name, r.counts[d.Name])
+	r.placeholders[value] = p
+	r.findings = append(r.findings, Finding{Path: path, Detector: d.Name, Secret: d.Secret, Placeholder: p, Preview: preview(value)})
+	return p
+}
+
+// preview masks all but the first and last characters of value
+func preview(value string) string {
+	if i := strings.IndexByte(value, '\n'); i >= 0 {
+		value = value[:i]
+	}
+	if len(value) <= 8 {
+		return strings.Repeat("*", len(value))
+	}
+	return value[:4] + "…" + value[len(value)-4:]
+}
+
+// Findings returns the values redacted so far, one per distinct value
+func (r *Redactor) Findings() []Finding {
+	return r.findings
+}
+
+// Secrets reports whether any finding is a secret rather than personal data
+func (r *Redactor) Secrets() bool {
+	for _, f := range r.findings {
+		if f.Secret {
+			return true
+		}
+	}
+	return false
+}
+
+// Report describes the findings for the user, one per line
+func Report(findings []Finding) string {
+	var b strings.Builder
+	for _, f := range findings {
+		kind := "personal data"
+		if f.Secret {
+			kind = "secret"
+		}
+		fmt.Fprintf(&b, "%s: %s %s (%s) → %s\n", f.Path, f.Detector, f.Preview, kind, f.Placeholder)
+	}
+	return b.String()
+}
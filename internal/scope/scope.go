@@ -0,0 +1,300 @@
+// Package scope infers the Conventional Commits scope of a change from the
+// paths it touches, using configured path rules and the packages of Go,
+// npm and Cargo workspaces.
+package scope
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dakoctba/cmt/internal/ignore"
+	"gopkg.in/yaml.v3"
+)
+
+// How a change touching several scopes is described
+const (
+	// MultipleJoin uses every scope, comma-separated: "api,web"
+	MultipleJoin = "join"
+
+	// MultipleOmit leaves the scope out
+	MultipleOmit = "omit"
+
+	// MultipleSplit suggests splitting the change into one commit per scope
+	// and otherwise joins the scopes
+	MultipleSplit = "split"
+)
+
+// Manifests are the files marking a workspace package, given to
+// git ls-files to find them anywhere in the repository
+var Manifests = []string{
+	"go.mod", "*/go.mod",
+	"package.json", "*/package.json",
+	"pnpm-workspace.yaml",
+	"Cargo.toml", "*/Cargo.toml",
+}
+
+// Package is a directory whose files share a scope
+type Package struct {
+	Dir   string
+	Scope string
+	Kind  string
+}
+
+// rule maps files matching a gitignore-style pattern to a scope
+type rule struct {
+	scope   string
+	pattern string
+	matcher *ignore.Matcher
+}
+
+// Resolver maps changed paths to scopes. Configured rules win over detected
+// packages; among rules the longest matching pattern wins, among packages
+// the deepest one containing the file.
+type Resolver struct {
+	rules    []rule
+	packages []Package
+}
+
+// New builds a resolver from scope rules: scope names mapped to the
+// gitignore-style patterns of their files, e.g. api: [services/api/]
+func New(rules map[string][]string, packages []Package) (*Resolver, error) {
+	r := &Resolver{packages: packages}
+	for scope, patterns := range rules {
+		for _, pattern := range patterns {
+			m, err := ignore.New([]string{pattern})
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q for scope %s: %w", pattern, scope, err)
+			}
+			r.rules = append(r.rules, rule{scope: scope, pattern: pattern, matcher: m})
+		}
+	}
+	sort.Slice(r.rules, func(i, j int) bool {
+		if len(r.rules[i].pattern) != len(r.rules[j].pattern) {
+			return len(r.rules[i].pattern) > len(r.rules[j].pattern)
+		}
+		return r.rules[i].pattern < r.rules[j].pattern
+	})
+	sort.SliceStable(r.packages, func(i, j int) bool {
+		return len(r.packages[i].Dir) > len(r.packages[j].Dir)
+	})
+	return r, nil
+}
+
+// Scope returns the scope of a slash-separated path relative to the
+// repository root, or an empty string when nothing covers it
+func (r *Resolver) Scope(file string) string {
+	for _, rule := range r.rules {
+		if rule.matcher.Match(file) {
+			return rule.scope
+		}
+	}
+	for _, p := range r.packages {
+		if strings.HasPrefix(file, p.Dir+"/") {
+			return p.Scope
+		}
+	}
+	return ""
+}
+
+// Result is the scopes of a change
+type Result struct {
+	// Scopes are the candidate scopes, the one covering most files first
+	Scopes []string
+
+	// Files are the changed files of each scope
+	Files map[string][]string
+}
+
+// Resolve finds the scopes of the changed files. Files no rule or package
+// covers do not count.
+func (r *Resolver) Resolve(files []string) Result {
+	res := Result{Files: map[string][]string{}}
+	for _, f := range files {
+		if s := r.Scope(f); s != "" {
+			if _, ok := res.Files[s]; !ok {
+				res.Scopes = append(res.Scopes, s)
+			}
+			res.Files[s] = append(res.Files[s], f)
+		}
+	}
+	sort.SliceStable(res.Scopes, func(i, j int) bool {
+		return len(res.Files[res.Scopes[i]]) > len(res.Files[res.Scopes[j]])
+	})
+	return res
+}
+
+// Scope returns the scope to use for the change given how several scopes
+// are handled: a single scope as is, several joined with commas unless
+// multiple is MultipleOmit
+func (res Result) Scope(multiple string) string {
+	if len(res.Scopes) > 1 && multiple == MultipleOmit {
+		return ""
+	}
+	return strings.Join(res.Scopes, ",")
+}
+
+// Detect finds the workspace packages of the repository at root from the
+// paths of its manifest files (see Manifests): nested Go modules, npm and
+// pnpm workspaces and Cargo workspace members. Each package's scope is the
+// name of its directory. Root manifests that cannot be read or parsed are
+// skipped together with their workspaces, and returned as problems.
+func Detect(root string, manifests []string) ([]Package, []error) {
+	var (
+		packages []Package
+		problems []error
+		seen     = map[string]bool{}
+	)
+	add := func(dir, kind string) {
+		if dir == "." || dir == "" || seen[dir] {
+			return
+		}
+		seen[dir] = true
+		packages = append(packages, Package{Dir: dir, Scope: path.Base(dir), Kind: kind})
+	}
+	dirsOf := func(name string) []string {
+		var dirs []string
+		for _, m := range manifests {
+			if path.Base(m) == name {
+				dirs = append(dirs, path.Dir(m))
+			}
+		}
+		return dirs
+	}
+
+	// Every Go module below the root is a package of a multi-module repository
+	for _, dir := range dirsOf("go.mod") {
+		add(dir, "go")
+	}
+
+	// npm, yarn and pnpm workspaces
+	npm, errs := npmWorkspaces(root)
+	problems = append(problems, errs...)
+	for _, dir := range dirsOf("package.json") {
+		if matchAny(npm, dir) {
+			add(dir, "npm")
+		}
+	}
+
+	// Cargo workspace members
+	cargo, err := cargoMembers(root)
+	if err != nil {
+		problems = append(problems, err)
+	}
+	for _, dir := range dirsOf("Cargo.toml") {
+		if matchAny(cargo, dir) {
+			add(dir, "cargo")
+		}
+	}
+	return packages, problems
+}
+
+// npmWorkspaces returns the workspace patterns of the root package.json
+// ("workspaces": [...] or {"packages": [...]}) and pnpm-workspace.yaml. A
+// file that cannot be read or parsed adds no patterns and is reported.
+func npmWorkspaces(root string) ([]string, []error) {
+	var (
+		patterns []string
+		problems []error
+	)
+
+	data, err := os.ReadFile(filepath.Join(root, "package.json"))
+	if err != nil && !os.IsNotExist(err) {
+		problems = append(problems, err)
+	}
+	if err == nil {
+		var pkg struct {
+			Workspaces json.RawMessage `json:"workspaces"`
+		}
+		if err := json.Unmarshal(data, &pkg); err != nil {
+			problems = append(problems, fmt.Errorf("failed to parse package.json: %v", err))
+		}
+		var list []string
+		var object struct {
+			Packages []string `json:"packages"`
+		}
+		if json.Unmarshal(pkg.Workspaces, &list) == nil {
+			patterns = append(patterns, list...)
+		} else if json.Unmarshal(pkg.Workspaces, &object) == nil {
+			patterns = append(patterns, object.Packages...)
+		}
+	}
+
+	data, err = os.ReadFile(filepath.Join(root, "pnpm-workspace.yaml"))
+	if err != nil && !os.IsNotExist(err) {
+		problems = append(problems, err)
+	}
+	if err == nil {
+		var pnpm struct {
+			Packages []string `yaml:"packages"`
+		}
+		if err := yaml.Unmarshal(data, &pnpm); err != nil {
+			problems = append(problems, fmt.Errorf("failed to parse pnpm-workspace.yaml: %v", err))
+		} else {
+			patterns = append(patterns, pnpm.Packages...)
+		}
+	}
+	return patterns, problems
+}
+
+var (
+	tomlSection = regexp.MustCompile(`(?m)^\s*\[([^\]]+)\]\s*$`)
+	tomlMembers = regexp.MustCompile(`(?s)\bmembers\s*=\s*\[(.*?)\]`)
+	tomlString  = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+)
+
+// cargoMembers returns the members patterns of the [workspace] section of
+// the root Cargo.toml
+func cargoMembers(root string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(root, "Cargo.toml"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Cut out the [workspace] table, up to the next table header
+	text := string(data)
+	var section string
+	bounds := tomlSection.FindAllStringSubmatchIndex(text, -1)
+	for i, b := range bounds {
+		if strings.TrimSpace(text[b[2]:b[3]]) != "workspace" {
+			continue
+		}
+		end := len(text)
+		if i+1 < len(bounds) {
+			end = bounds[i+1][0]
+		}
+		section = text[b[1]:end]
+	}
+
+	match := tomlMembers.FindStringSubmatch(section)
+	if match == nil {
+		return nil, nil
+	}
+	var members []string
+	for _, s := range tomlString.FindAllStringSubmatch(match[1], -1) {
+		members = append(members, s[1]+s[2])
+	}
+	return members, nil
+}
+
+// matchAny reports whether dir matches one of the workspace patterns, such
+// as "packages/*" or "apps/web". Negated patterns exclude directories.
+func matchAny(patterns []string, dir string) bool {
+	matched := false
+	for _, p := range patterns {
+		negated := strings.HasPrefix(p, "!")
+		p = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(p, "!"), "./"), "/")
+		if ok, _ := path.Match(p, dir); ok || (strings.HasSuffix(p, "/**") && strings.HasPrefix(dir, strings.TrimSuffix(p, "**"))) {
+			matched = !negated
+		}
+	}
+	return matched
+}
@@ -0,0 +1,160 @@
+package scope
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	rules := map[string][]string{
+		"api":   {"services/api/"},
+		"auth":  {"services/api/auth/"},
+		"infra": {"*.tf", "deploy/"},
+	}
+	packages := []Package{
+		{Dir: "web", Scope: "web", Kind: "npm"},
+		{Dir: "web/packages/ui", Scope: "ui", Kind: "npm"},
+	}
+
+	tests := []struct {
+		name      string
+		files     []string
+		want      []string
+		wantScope string
+		multiple  string
+	}{
+		{
+			name:      "should map files with the rules",
+			files:     []string{"services/api/main.go", "services/api/routes.go"},
+			want:      []string{"api"},
+			wantScope: "api",
+		},
+		{
+			name:      "should prefer the most specific rule",
+			files:     []string{"services/api/auth/token.go"},
+			want:      []string{"auth"},
+			wantScope: "auth",
+		},
+		{
+			name:      "should fall back to the deepest package",
+			files:     []string{"web/packages/ui/button.tsx", "web/index.ts", "web/app.ts"},
+			want:      []string{"web", "ui"},
+			wantScope: "web,ui",
+		},
+		{
+			name:      "should omit several scopes when configured",
+			files:     []string{"main.tf", "web/index.ts"},
+			want:      []string{"infra", "web"},
+			multiple:  MultipleOmit,
+			wantScope: "",
+		},
+		{
+			name:  "should ignore files without a scope",
+			files: []string{"README.md"},
+		},
+	}
+
+	r, err := New(rules, packages)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := r.Resolve(tt.files)
+			if !reflect.DeepEqual(res.Scopes, tt.want) {
+				t.Errorf("Resolve() = %v, want %v", res.Scopes, tt.want)
+			}
+			if got := res.Scope(tt.multiple); got != tt.wantScope {
+				t.Errorf("Scope(%q) = %q, want %q", tt.multiple, got, tt.wantScope)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		manifests []string
+		want      []Package
+
+		// wantProblems is the number of manifests reported as broken
+		wantProblems int
+	}{
+		{
+			name:      "should find nested Go modules",
+			files:     map[string]string{"go.mod": "module example.com/app"},
+			manifests: []string{"go.mod", "tools/lint/go.mod", "api/go.mod"},
+			want:      []Package{{"tools/lint", "lint", "go"}, {"api", "api", "go"}},
+		},
+		{
+			name:      "should find npm workspaces",
+			files:     map[string]string{"package.json": `{"workspaces": ["packages/*", "!packages/legacy"]}`},
+			manifests: []string{"package.json", "packages/ui/package.json", "packages/legacy/package.json", "docs/package.json"},
+			want:      []Package{{"packages/ui", "ui", "npm"}},
+		},
+		{
+			name:      "should find yarn workspaces given as an object",
+			files:     map[string]string{"package.json": `{"workspaces": {"packages": ["apps/**"]}}`},
+			manifests: []string{"package.json", "apps/web/package.json"},
+			want:      []Package{{"apps/web", "web", "npm"}},
+		},
+		{
+			name:      "should find pnpm workspaces",
+			files:     map[string]string{"pnpm-workspace.yaml": "packages:\n  - 'apps/*'\n"},
+			manifests: []string{"pnpm-workspace.yaml", "apps/site/package.json"},
+			want:      []Package{{"apps/site", "site", "npm"}},
+		},
+		{
+			name: "should find Cargo workspace members",
+			files: map[string]string{"Cargo.toml": `[workspace]
+members = [
+    "crates/*",
+    "cli",
+]
+
+[workspace.dependencies]
+serde = "1"
+`},
+			manifests: []string{"Cargo.toml", "crates/core/Cargo.toml", "cli/Cargo.toml", "examples/demo/Cargo.toml"},
+			want:      []Package{{"crates/core", "core", "cargo"}, {"cli", "cli", "cargo"}},
+		},
+		{
+			name: "should skip broken manifests and keep the other workspaces",
+			files: map[string]string{
+				"package.json":        `{"workspaces": [`,
+				"pnpm-workspace.yaml": "packages:\n  - 'apps/*'\n",
+			},
+			manifests:    []string{"package.json", "pnpm-workspace.yaml", "apps/site/package.json", "api/go.mod"},
+			want:         []Package{{"api", "api", "go"}, {"apps/site", "site", "npm"}},
+			wantProblems: 1,
+		},
+		{
+			name:         "should skip a broken pnpm workspace",
+			files:        map[string]string{"pnpm-workspace.yaml": "packages: [\n"},
+			manifests:    []string{"pnpm-workspace.yaml", "apps/site/package.json"},
+			wantProblems: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+					t.Fatalf("Failed to write %s: %v", name, err)
+				}
+			}
+
+			got, problems := Detect(root, tt.manifests)
+			if len(problems) != tt.wantProblems {
+				t.Errorf("Detect() problems = %v, want %d", problems, tt.wantProblems)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Detect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
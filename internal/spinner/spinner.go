@@ -2,45 +2,97 @@ package spinner
 
 import (
 	"fmt"
+	"os"
+	"sync"
 	"time"
+
+	"github.com/dakoctba/cmt/internal/ui"
 )
 
-// Spinner represents a loading spinner
+// Spinner represents a loading spinner. It draws on stderr, leaving stdout
+// to the command's output.
 type Spinner struct {
-	done chan bool
+	done    chan bool
+	stopped chan struct{}
+	started bool
+	once    sync.Once
+
+	// plain is set when stderr is not a terminal, e.g. in CI logs: the
+	// message is printed once, without animation or control codes
+	plain bool
+
+	mu   sync.Mutex
+	text string
 }
 
 // New creates a new spinner instance
 func New() *Spinner {
 	return &Spinner{
-		done: make(chan bool),
+		done:    make(chan bool),
+		stopped: make(chan struct{}),
+		plain:   !ui.IsTerminal(os.Stderr),
 	}
 }
 
 // Start begins the spinner animation
 func (s *Spinner) Start(model string) {
-	go s.run(model)
+	s.StartMessage(fmt.Sprintf("Thinking with %s model...", model))
+}
+
+// StartMessage begins the spinner animation with a custom text
+func (s *Spinner) StartMessage(text string) {
+	s.SetMessage(text)
+	if s.plain {
+		fmt.Fprintln(os.Stderr, text)
+		return
+	}
+	s.started = true
+	go s.run()
+}
+
+// SetMessage changes the text shown next to the spinner
+func (s *Spinner) SetMessage(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.text = text
+}
+
+func (s *Spinner) message() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.text
 }
 
-// Stop stops the spinner animation
+// Stop stops the spinner animation and clears its line. It is safe to call
+// more than once, e.g. when streamed output replaces the spinner early.
 func (s *Spinner) Stop() {
-	s.done <- true
+	s.once.Do(func() {
+		close(s.done)
+		if s.started {
+			<-s.stopped
+		}
+	})
 }
 
-func (s *Spinner) run(model string) {
+func (s *Spinner) run() {
+	defer close(s.stopped)
+
 	spinner := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 	i := 0
 
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
 	for {
+		fmt.Fprintf(os.Stderr, "\r\033[K🤔 %s %s", spinner[i], s.message())
+		i = (i + 1) % len(spinner)
+
 		select {
 		case <-s.done:
 			// Clear the entire line and move to next line
-			fmt.Print("\r\033[K")
+			fmt.Fprint(os.Stderr, "\r\033[K")
 			return
-		default:
-			fmt.Printf("\r🤔 %s Thinking with %s model...", spinner[i], model)
-			time.Sleep(100 * time.Millisecond)
-			i = (i + 1) % len(spinner)
+		case <-ticker.C:
 		}
 	}
 }
@@ -0,0 +1,236 @@
+// Package style learns a repository's commit conventions from its history,
+// so that generated messages read like the ones already there.
+package style
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dakoctba/cmt/internal/git"
+	"github.com/dakoctba/cmt/internal/message"
+)
+
+// Count is how many of the sampled commits use a type, scope or footer
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Style describes the conventions found in a sample of commit messages
+type Style struct {
+	// Commits is the number of messages analysed; Conventional is how many
+	// of them follow the Conventional Commits format
+	Commits      int `json:"commits"`
+	Conventional int `json:"conventional"`
+
+	// Types, Scopes and Footers are sorted from most to least used
+	Types   []Count `json:"types"`
+	Scopes  []Count `json:"scopes"`
+	Footers []Count `json:"footers"`
+
+	// AverageSubjectLength is the mean length of the subject lines, type and
+	// scope included, in characters, rounded to the nearest integer
+	AverageSubjectLength int `json:"average_subject_length"`
+
+	// Traits are the style rules the history follows, in plain English
+	Traits []string `json:"traits"`
+
+	// Examples are the header and body of recent messages showing the
+	// style. Their trailers, which name people and their email addresses,
+	// are left out.
+	Examples []string `json:"examples"`
+}
+
+// Thresholds above which a share of the commits makes a trait. Below the
+// lower one the opposite trait is reported.
+const (
+	mostly = 0.8
+	often  = 0.5
+	rarely = 0.2
+)
+
+// Analyze derives the style of messages, newest first. Merge commits and
+// fixup!/squash! commits are skipped. Up to examples messages are kept as
+// examples, preferring different types.
+func Analyze(messages []string, examples int) Style {
+	var (
+		s       Style
+		types   = map[string]int{}
+		scopes  = map[string]int{}
+		footers = map[string]int{}
+
+		subjectLength, lower, upper, period, bodies, scoped int
+		conventional                                        []*message.Message
+	)
+
+	for _, text := range messages {
+		text = strings.TrimSpace(text)
+		if skip(text) {
+			continue
+		}
+		m, err := message.ParseText(text)
+		if err != nil {
+			continue
+		}
+
+		s.Commits++
+		subjectLength += len([]rune(m.Header()))
+		if first := []rune(m.Subject); len(first) > 0 {
+			switch {
+			case unicode.IsLower(first[0]):
+				lower++
+			case unicode.IsUpper(first[0]):
+				upper++
+			}
+		}
+		if strings.HasSuffix(m.Subject, ".") {
+			period++
+		}
+		if m.Body != "" {
+			bodies++
+		}
+		for _, f := range m.Footers {
+			footers[f.Token]++
+		}
+
+		if m.Type == "" {
+			continue
+		}
+		s.Conventional++
+		conventional = append(conventional, m)
+		types[strings.ToLower(m.Type)]++
+		if m.Scope != "" {
+			scoped++
+			for _, scope := range strings.Split(m.Scope, ",") {
+				scopes[strings.TrimSpace(scope)]++
+			}
+		}
+	}
+	if s.Commits == 0 {
+		return s
+	}
+
+	s.Types = counts(types)
+	s.Scopes = counts(scopes)
+	s.Footers = counts(footers)
+	s.AverageSubjectLength = (subjectLength + s.Commits/2) / s.Commits
+
+	share := func(n, of int) float64 {
+		if of == 0 {
+			return 0
+		}
+		return float64(n) / float64(of)
+	}
+	switch {
+	case share(lower, s.Commits) >= mostly:
+		s.Traits = append(s.Traits, "subjects start with a lowercase letter")
+	case share(upper, s.Commits) >= mostly:
+		s.Traits = append(s.Traits, "subjects start with a capital letter")
+	}
+	switch {
+	case share(period, s.Commits) >= mostly:
+		s.Traits = append(s.Traits, "subjects end with a period")
+	case share(period, s.Commits) <= rarely:
+		s.Traits = append(s.Traits, "subjects do not end with a period")
+	}
+	switch {
+	case share(bodies, s.Commits) >= often:
+		s.Traits = append(s.Traits, "most commits have a body explaining the change")
+	case share(bodies, s.Commits) <= rarely:
+		s.Traits = append(s.Traits, "commits rarely have a body, the subject line is enough")
+	}
+	switch {
+	case share(scoped, s.Conventional) >= often:
+		s.Traits = append(s.Traits, "most commits have a scope")
+	case s.Conventional > 0 && share(scoped, s.Conventional) <= rarely:
+		s.Traits = append(s.Traits, "commits rarely have a scope")
+	}
+	for _, f := range s.Footers {
+		if share(f.Count, s.Commits) >= often {
+			s.Traits = append(s.Traits, "commits usually end with a "+f.Name+" trailer")
+		}
+	}
+	s.Traits = append(s.Traits, "subjects are about "+strconv.Itoa(s.AverageSubjectLength)+" characters long")
+
+	s.Examples = pick(conventional, examples)
+	return s
+}
+
+// ScopeNames returns the names of the scopes in use, most used first
+func (s Style) ScopeNames() []string {
+	names := make([]string, len(s.Scopes))
+	for i, c := range s.Scopes {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// skip reports whether a message says nothing about the repository's style
+func skip(text string) bool {
+	return text == "" ||
+		strings.HasPrefix(text, "Merge ") ||
+		strings.HasPrefix(text, "fixup! ") ||
+		strings.HasPrefix(text, "squash! ") ||
+		strings.HasPrefix(text, "amend! ")
+}
+
+// pick chooses up to n messages, newest first, taking one of each type
+// before repeating a type, and returns them without their footers
+func pick(messages []*message.Message, n int) []string {
+	var (
+		picked []string
+		used   = map[int]bool{}
+		seen   = map[string]bool{}
+	)
+	for pass := 0; pass < 2; pass++ {
+		for i, m := range messages {
+			if len(picked) >= n {
+				return picked
+			}
+			if used[i] || (pass == 0 && seen[m.Type]) {
+				continue
+			}
+			used[i], seen[m.Type] = true, true
+			example := *m
+			example.Footers = nil
+			picked = append(picked, example.String())
+		}
+	}
+	return picked
+}
+
+// counts sorts the counted names from most to least used, then by name
+func counts(m map[string]int) []Count {
+	out := make([]Count, 0, len(m))
+	for name, count := range m {
+		out = append(out, Count{Name: name, Count: count})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// Learn analyses the latest count commits of the current branch, keeping up
+// to examples of them as examples. A count of zero or less, or a repository
+// without commits, gives an empty Style.
+func Learn(ctx context.Context, count, examples int) (Style, error) {
+	if count <= 0 || !git.HasCommits(ctx) {
+		return Style{}, nil
+	}
+	commits, err := git.Log(ctx, "", count)
+	if err != nil {
+		return Style{}, err
+	}
+	messages := make([]string, len(commits))
+	for i, c := range commits {
+		messages[i] = c.Message
+	}
+	return Analyze(messages, examples), nil
+}
@@ -0,0 +1,106 @@
+package style
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	history := []string{
+		"feat(api): add users endpoint\n\nList and create users.\n\nRefs: PROJ-12",
+		"fix(api): reject empty names\n\nRefs: PROJ-13\nSigned-off-by: Jane Doe <jane@example.com>",
+		"Merge branch 'main' into feature",
+		"fixup! fix(api): reject empty names",
+		"docs: describe the users endpoint",
+		"feat(web): show the users page\n\nRefs: PROJ-14",
+	}
+
+	tests := []struct {
+		name     string
+		messages []string
+		examples int
+		check    func(t *testing.T, s Style)
+	}{
+		{
+			name:     "should count types and scopes",
+			messages: history,
+			examples: 2,
+			check: func(t *testing.T, s Style) {
+				if s.Commits != 4 || s.Conventional != 4 {
+					t.Errorf("Commits, Conventional = %d, %d, want 4, 4", s.Commits, s.Conventional)
+				}
+				wantTypes := []Count{{"feat", 2}, {"docs", 1}, {"fix", 1}}
+				if !reflect.DeepEqual(s.Types, wantTypes) {
+					t.Errorf("Types = %v, want %v", s.Types, wantTypes)
+				}
+				if got, want := s.ScopeNames(), []string{"api", "web"}; !reflect.DeepEqual(got, want) {
+					t.Errorf("ScopeNames() = %v, want %v", got, want)
+				}
+			},
+		},
+		{
+			name:     "should detect traits",
+			messages: history,
+			examples: 2,
+			check: func(t *testing.T, s Style) {
+				want := []string{
+					"subjects start with a lowercase letter",
+					"subjects do not end with a period",
+					"most commits have a scope",
+					"commits usually end with a Refs trailer",
+					"subjects are about 30 characters long",
+				}
+				if !reflect.DeepEqual(s.Traits, want) {
+					t.Errorf("Traits = %q, want %q", s.Traits, want)
+				}
+			},
+		},
+		{
+			name:     "should prefer examples of different types without their trailers",
+			messages: history,
+			examples: 3,
+			check: func(t *testing.T, s Style) {
+				want := []string{
+					"feat(api): add users endpoint\n\nList and create users.",
+					"fix(api): reject empty names",
+					"docs: describe the users endpoint",
+				}
+				if !reflect.DeepEqual(s.Examples, want) {
+					t.Errorf("Examples = %q, want %q", s.Examples, want)
+				}
+			},
+		},
+		{
+			name:     "should detect capitalised subjects with periods",
+			messages: []string{"Add login page.", "Fix typo in README.", "Update dependencies."},
+			check: func(t *testing.T, s Style) {
+				if s.Conventional != 0 || len(s.Types) != 0 {
+					t.Errorf("Conventional = %d, Types = %v, want none", s.Conventional, s.Types)
+				}
+				want := []string{
+					"subjects start with a capital letter",
+					"subjects end with a period",
+					"commits rarely have a body, the subject line is enough",
+					"subjects are about 18 characters long",
+				}
+				if !reflect.DeepEqual(s.Traits, want) {
+					t.Errorf("Traits = %q, want %q", s.Traits, want)
+				}
+			},
+		},
+		{
+			name: "should handle an empty history",
+			check: func(t *testing.T, s Style) {
+				if !reflect.DeepEqual(s, Style{}) {
+					t.Errorf("Analyze() = %+v, want an empty style", s)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, Analyze(tt.messages, tt.examples))
+		})
+	}
+}
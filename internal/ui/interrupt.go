@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync/atomic"
+)
+
+// ignoring counts the programs running in the foreground that handle
+// Ctrl-C themselves, see IgnoreInterrupts
+var ignoring atomic.Int32
+
+// NotifyInterrupt returns a copy of ctx that is cancelled when the user
+// presses Ctrl-C, except while IgnoreInterrupts is in effect. stop cancels
+// the context and gives Ctrl-C its default action back.
+func NotifyInterrupt(ctx context.Context) (_ context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		for {
+			select {
+			case <-interrupts:
+				if ignoring.Load() == 0 {
+					cancel()
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(interrupts)
+		cancel()
+	}
+}
+
+// IgnoreInterrupts keeps Ctrl-C from cancelling the context of
+// NotifyInterrupt until the returned function is called. Like git, cmt
+// leaves Ctrl-C to the editor while it runs, so that the editor can restore
+// the terminal and keep the user's edits.
+func IgnoreInterrupts() (restore func()) {
+	ignoring.Add(1)
+	return func() { ignoring.Add(-1) }
+}
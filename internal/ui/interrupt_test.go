@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNotifyInterrupt(t *testing.T) {
+	tests := []struct {
+		name       string
+		ignore     bool
+		wantCancel bool
+	}{
+		{
+			name:       "should cancel the context on Ctrl-C",
+			wantCancel: true,
+		},
+		{
+			name:   "should leave Ctrl-C to the editor",
+			ignore: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, stop := NotifyInterrupt(context.Background())
+			defer stop()
+			if tt.ignore {
+				restore := IgnoreInterrupts()
+				defer restore()
+			}
+
+			self, err := os.FindProcess(os.Getpid())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := self.Signal(os.Interrupt); err != nil {
+				t.Skipf("cannot interrupt the test: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				if !tt.wantCancel {
+					t.Error("context cancelled while Ctrl-C is ignored")
+				}
+			case <-time.After(200 * time.Millisecond):
+				if tt.wantCancel {
+					t.Error("context not cancelled on Ctrl-C")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package ui
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Choice is a single answer offered by Prompter.Choose
+type Choice struct {
+	Key   string
+	Label string
+}
+
+// Prompter asks the user questions on a terminal
+type Prompter struct {
+	ctx context.Context
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewPrompter creates a prompter reading answers from in and writing
+// questions to out. Questions return ctx's error as soon as it is
+// cancelled, without waiting for an answer.
+func NewPrompter(ctx context.Context, in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{ctx: ctx, in: bufio.NewReader(in), out: out}
+}
+
+// IsTerminal reports whether f is attached to an interactive terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+
+	// The null device is a character device too, but nobody is typing there
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(info, null) {
+		return false
+	}
+	return true
+}
+
+// Choose asks question until the user answers with one of the choice keys
+// and returns that key. An empty answer selects the first choice.
+func (p *Prompter) Choose(question string, choices []Choice) (string, error) {
+	labels := make([]string, len(choices))
+	for i, c := range choices {
+		labels[i] = fmt.Sprintf("[%s] %s", c.Key, c.Label)
+	}
+
+	for {
+		fmt.Fprintf(p.out, "%s %s: ", question, strings.Join(labels, ", "))
+		answer, err := p.readLine()
+		if err != nil {
+			return "", err
+		}
+		if answer == "" && len(choices) > 0 {
+			return choices[0].Key, nil
+		}
+		for _, c := range choices {
+			if strings.EqualFold(answer, c.Key) || strings.EqualFold(answer, c.Label) {
+				return c.Key, nil
+			}
+		}
+		fmt.Fprintf(p.out, "Please answer one of: %s\n", strings.Join(keys(choices), ", "))
+	}
+}
+
+// Select asks question until the user answers with a number from 1 to n
+// or one of the choice keys. It returns the zero-based index of the chosen
+// number, or -1 and the key of the chosen choice. An empty answer selects
+// the first number.
+func (p *Prompter) Select(question string, n int, choices []Choice) (int, string, error) {
+	labels := []string{fmt.Sprintf("[1-%d] pick", n)}
+	for _, c := range choices {
+		labels = append(labels, fmt.Sprintf("[%s] %s", c.Key, c.Label))
+	}
+
+	for {
+		fmt.Fprintf(p.out, "%s %s: ", question, strings.Join(labels, ", "))
+		answer, err := p.readLine()
+		if err != nil {
+			return -1, "", err
+		}
+		if answer == "" {
+			return 0, "", nil
+		}
+		if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= n {
+			return i - 1, "", nil
+		}
+		for _, c := range choices {
+			if strings.EqualFold(answer, c.Key) || strings.EqualFold(answer, c.Label) {
+				return -1, c.Key, nil
+			}
+		}
+		fmt.Fprintf(p.out, "Please answer a number from 1 to %d or one of: %s\n", n, strings.Join(keys(choices), ", "))
+	}
+}
+
+// Confirm asks a yes/no question, returning def on an empty answer
+func (p *Prompter) Confirm(question string, def bool) (bool, error) {
+	hint := "[y/N]"
+	if def {
+		hint = "[Y/n]"
+	}
+
+	for {
+		fmt.Fprintf(p.out, "%s %s ", question, hint)
+		answer, err := p.readLine()
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
+
+// readLine reads an answer. A read left pending by a cancelled context
+// keeps the reader busy, so the prompter must not be used afterwards.
+func (p *Prompter) readLine() (string, error) {
+	type answer struct {
+		line string
+		err  error
+	}
+	answers := make(chan answer, 1)
+	go func() {
+		line, err := p.in.ReadString('\n')
+		answers <- answer{line, err}
+	}()
+
+	var line string
+	var err error
+	select {
+	case <-p.ctx.Done():
+		return "", p.ctx.Err()
+	case a := <-answers:
+		line, err = a.line, a.err
+	}
+	if err != nil && (err != io.EOF || line == "") {
+		if err == io.EOF {
+			return "", fmt.Errorf("no answer given: input closed")
+		}
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func keys(choices []Choice) []string {
+	out := make([]string, len(choices))
+	for i, c := range choices {
+		out[i] = c.Key
+	}
+	return out
+}
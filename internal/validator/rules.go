@@ -0,0 +1,223 @@
+package validator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dakoctba/cmt/internal/message"
+	"gopkg.in/yaml.v3"
+)
+
+// Level is the severity of a rule, using commitlint's numbering
+type Level int
+
+const (
+	// Disabled rules are not checked
+	Disabled Level = 0
+
+	// Warning rules are reported but do not make a message invalid
+	Warning Level = 1
+
+	// Error rules make a message invalid
+	Error Level = 2
+)
+
+func (l Level) String() string {
+	switch l {
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	}
+	return "disabled"
+}
+
+// Symbol returns the mark used when printing violations of this level
+func (l Level) Symbol() string {
+	if l == Error {
+		return "✖"
+	}
+	return "⚠"
+}
+
+// Rule is a single commitlint rule setting: [level, "always"|"never", value]
+type Rule struct {
+	Level Level
+	Never bool
+	Value any
+}
+
+// Rules maps commitlint rule names to their settings
+type Rules map[string]Rule
+
+// ConfigFiles are the commitlint configuration files looked up in the
+// repository root, in order of preference
+var ConfigFiles = []string{
+	".commitlintrc",
+	".commitlintrc.json",
+	".commitlintrc.yaml",
+	".commitlintrc.yml",
+	"commitlint.config.json",
+	"commitlint.config.yaml",
+	"commitlint.config.yml",
+}
+
+// DefaultRules returns the rules of @commitlint/config-conventional
+func DefaultRules() Rules {
+	return Rules{
+		"body-leading-blank":     {Level: Warning},
+		"body-max-line-length":   {Level: Error, Value: 100},
+		"footer-leading-blank":   {Level: Warning},
+		"footer-max-line-length": {Level: Error, Value: 100},
+		"footer-breaking-change": {Level: Error},
+		"header-max-length":      {Level: Error, Value: 100},
+		"subject-case":           {Level: Error, Never: true, Value: []string{"sentence-case", "start-case", "pascal-case", "upper-case"}},
+		"subject-empty":          {Level: Error, Never: true},
+		"subject-full-stop":      {Level: Error, Never: true, Value: "."},
+		"type-case":              {Level: Error, Value: "lower-case"},
+		"type-empty":             {Level: Error, Never: true},
+		"type-enum":              {Level: Error, Value: append([]string(nil), message.CommonTypes...)},
+		"scope-case":             {Level: Error, Value: "lower-case"},
+	}
+}
+
+// LoadRules returns the rules configured for the repository in dir. Without
+// a commitlint configuration file the conventional defaults apply.
+func LoadRules(dir string) (Rules, string, error) {
+	for _, name := range ConfigFiles {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read %s: %v", path, err)
+		}
+
+		rules, err := ParseRules(data)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid commitlint config %s: %w", path, err)
+		}
+		return rules, path, nil
+	}
+	return DefaultRules(), "", nil
+}
+
+// ParseRules reads a commitlint configuration in JSON or YAML. Rules from
+// an extended @commitlint/config-conventional are used as the base.
+func ParseRules(data []byte) (Rules, error) {
+	var cfg struct {
+		Extends any                  `yaml:"extends"`
+		Rules   map[string]yaml.Node `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+
+	rules := Rules{}
+	if extendsConventional(cfg.Extends) {
+		rules = DefaultRules()
+	}
+
+	for name, node := range cfg.Rules {
+		var setting []any
+		if err := node.Decode(&setting); err != nil {
+			return nil, fmt.Errorf("rule %s (line %d): expected [level, when, value]", name, node.Line)
+		}
+		rule, err := parseRule(setting)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s (line %d): %v", name, node.Line, err)
+		}
+		rules[name] = rule
+	}
+	return rules, nil
+}
+
+func parseRule(setting []any) (Rule, error) {
+	if len(setting) == 0 {
+		return Rule{}, fmt.Errorf("missing level")
+	}
+
+	level, ok := setting[0].(int)
+	if !ok || level < 0 || level > 2 {
+		return Rule{}, fmt.Errorf("level must be 0, 1 or 2")
+	}
+	rule := Rule{Level: Level(level)}
+
+	if len(setting) > 1 {
+		switch setting[1] {
+		case "always":
+		case "never":
+			rule.Never = true
+		default:
+			return Rule{}, fmt.Errorf(`applicability must be "always" or "never"`)
+		}
+	}
+	if len(setting) > 2 {
+		rule.Value = setting[2]
+	}
+	return rule, nil
+}
+
+func extendsConventional(extends any) bool {
+	var names []string
+	switch v := extends.(type) {
+	case string:
+		names = []string{v}
+	case []any:
+		for _, name := range v {
+			if s, ok := name.(string); ok {
+				names = append(names, s)
+			}
+		}
+	}
+	for _, name := range names {
+		if strings.Contains(name, "config-conventional") {
+			return true
+		}
+	}
+	return false
+}
+
+// intValue returns the rule value as an int, or def when it is not a number
+func (r Rule) intValue(def int) int {
+	switch v := r.Value.(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return def
+}
+
+// stringsValue returns the rule value as a list of strings
+func (r Rule) stringsValue() []string {
+	switch v := r.Value.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// Types returns the commit types the rules allow: the type-enum list, or
+// the common Conventional Commits types when the rules do not restrict them
+func (r Rules) Types() []string {
+	if rule, ok := r["type-enum"]; ok && rule.Level != Disabled && !rule.Never {
+		if types := rule.stringsValue(); len(types) > 0 {
+			return types
+		}
+	}
+	return message.CommonTypes
+}
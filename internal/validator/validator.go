@@ -0,0 +1,304 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/dakoctba/cmt/internal/message"
+)
+
+// Violation is a rule a message does not satisfy
+type Violation struct {
+	Rule    string `json:"rule"`
+	Level   Level  `json:"level"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s [%s]", v.Message, v.Rule)
+}
+
+// Result holds the violations found in a message
+type Result struct {
+	Violations []Violation `json:"violations"`
+}
+
+// Valid reports whether the message has no error-level violations
+func (r Result) Valid() bool {
+	return len(r.Errors()) == 0
+}
+
+// Errors returns the error-level violations
+func (r Result) Errors() []Violation {
+	var out []Violation
+	for _, v := range r.Violations {
+		if v.Level == Error {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Score rates the message for ranking: lower is better, errors weigh ten
+// times as much as warnings
+func (r Result) Score() int {
+	score := 0
+	for _, v := range r.Violations {
+		if v.Level == Error {
+			score += 10
+		} else {
+			score++
+		}
+	}
+	return score
+}
+
+// Feedback describes the violations as a list suitable for a model prompt
+func (r Result) Feedback() string {
+	lines := make([]string, len(r.Violations))
+	for i, v := range r.Violations {
+		lines[i] = "- " + v.Message
+	}
+	return strings.Join(lines, "\n")
+}
+
+// breakingPattern matches lines that attempt a breaking change footer
+var breakingPattern = regexp.MustCompile(`^(?:BREAKING[ _-]?CHANGES?\b|(?i:breaking[ _-]?changes?)\s*[:#])`)
+
+// Validate checks a commit message against the rules
+func (rules Rules) Validate(text string) Result {
+	text = strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	lines := strings.Split(text, "\n")
+	header := lines[0]
+
+	m, err := message.ParseText(text)
+	if err != nil {
+		m = &message.Message{}
+	}
+
+	v := &checker{rules: rules}
+
+	// Header
+	if rule, ok := v.rule("header-max-length"); ok {
+		if max := rule.intValue(100); len([]rune(header)) > max {
+			v.report("header-max-length", rule, fmt.Sprintf("header must not be longer than %d characters, current length is %d", max, len([]rune(header))))
+		}
+	}
+
+	// Type
+	v.empty("type-empty", "type", m.Type)
+	if m.Type != "" {
+		v.caseOf("type-case", "type", m.Type)
+		if rule, ok := v.rule("type-enum"); ok {
+			allowed := rule.stringsValue()
+			if contains(allowed, m.Type) == rule.Never && len(allowed) > 0 {
+				v.report("type-enum", rule, fmt.Sprintf("type must %sbe one of [%s]", not(rule), strings.Join(allowed, ", ")))
+			}
+		}
+	}
+
+	// Scope
+	v.empty("scope-empty", "scope", m.Scope)
+	if m.Scope != "" {
+		for _, scope := range splitScopes(m.Scope) {
+			v.caseOf("scope-case", "scope", scope)
+		}
+		if rule, ok := v.rule("scope-enum"); ok {
+			allowed := rule.stringsValue()
+			for _, scope := range splitScopes(m.Scope) {
+				if len(allowed) > 0 && contains(allowed, scope) == rule.Never {
+					v.report("scope-enum", rule, fmt.Sprintf("scope must %sbe one of [%s]", not(rule), strings.Join(allowed, ", ")))
+					break
+				}
+			}
+		}
+	}
+
+	// Subject
+	v.empty("subject-empty", "subject", m.Subject)
+	if m.Subject != "" {
+		v.caseOf("subject-case", "subject", m.Subject)
+		if rule, ok := v.rule("subject-full-stop"); ok {
+			stop := "."
+			if s := rule.stringsValue(); len(s) > 0 {
+				stop = s[0]
+			}
+			if strings.HasSuffix(m.Subject, stop) == rule.Never {
+				v.report("subject-full-stop", rule, fmt.Sprintf("subject must %send with %q", not(rule), stop))
+			}
+		}
+		if rule, ok := v.rule("subject-max-length"); ok {
+			if max := rule.intValue(72); len([]rune(m.Subject)) > max {
+				v.report("subject-max-length", rule, fmt.Sprintf("subject must not be longer than %d characters", max))
+			}
+		}
+	}
+
+	// Body and footers
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		if rule, ok := v.rule("body-leading-blank"); ok && !rule.Never {
+			v.report("body-leading-blank", rule, "body must have a leading blank line")
+		}
+	}
+	if start := footerStart(lines); start > 1 && strings.TrimSpace(lines[start-1]) != "" {
+		if rule, ok := v.rule("footer-leading-blank"); ok && !rule.Never {
+			v.report("footer-leading-blank", rule, "footer must have a leading blank line")
+		}
+	}
+
+	v.maxLineLength("body-max-line-length", "body", m.Body)
+	v.maxLineLength("footer-max-line-length", "footer", m.FooterText())
+
+	if rule, ok := v.rule("footer-breaking-change"); ok {
+		for _, line := range lines[1:] {
+			if breakingPattern.MatchString(line) && !isBreakingFooter(line) {
+				v.report("footer-breaking-change", rule, `breaking changes must be written as "BREAKING CHANGE: <description>"`)
+				break
+			}
+		}
+	}
+
+	return Result{Violations: v.violations}
+}
+
+type checker struct {
+	rules      Rules
+	violations []Violation
+}
+
+func (c *checker) rule(name string) (Rule, bool) {
+	rule, ok := c.rules[name]
+	return rule, ok && rule.Level != Disabled
+}
+
+func (c *checker) report(name string, rule Rule, msg string) {
+	c.violations = append(c.violations, Violation{Rule: name, Level: rule.Level, Message: msg})
+}
+
+func (c *checker) empty(name, field, value string) {
+	rule, ok := c.rule(name)
+	if !ok {
+		return
+	}
+	if (value == "") == rule.Never {
+		c.report(name, rule, fmt.Sprintf("%s must %sbe empty", field, not(rule)))
+	}
+}
+
+func (c *checker) caseOf(name, field, value string) {
+	rule, ok := c.rule(name)
+	if !ok {
+		return
+	}
+	cases := rule.stringsValue()
+	if len(cases) == 0 {
+		return
+	}
+
+	matched := false
+	for _, name := range cases {
+		if hasCase(value, name) {
+			matched = true
+			break
+		}
+	}
+	if matched == rule.Never {
+		c.report(name, rule, fmt.Sprintf("%s must %sbe %s", field, not(rule), strings.Join(cases, ", ")))
+	}
+}
+
+func (c *checker) maxLineLength(name, field, text string) {
+	rule, ok := c.rule(name)
+	if !ok || text == "" {
+		return
+	}
+	max := rule.intValue(100)
+	for _, line := range strings.Split(text, "\n") {
+		// URLs cannot be wrapped, so commitlint lets them through
+		if len([]rune(line)) > max && !strings.Contains(line, "://") {
+			c.report(name, rule, fmt.Sprintf("%s lines must not be longer than %d characters", field, max))
+			return
+		}
+	}
+}
+
+// footerStart returns the index of the first line of the trailing footer
+// block, or -1 when the message has no footers
+func footerStart(lines []string) int {
+	start := -1
+	for i := len(lines) - 1; i > 0; i-- {
+		if strings.TrimSpace(lines[i]) == "" {
+			break
+		}
+		if _, ok := message.ParseFooter(lines[i]); ok {
+			start = i
+		}
+	}
+	return start
+}
+
+func isBreakingFooter(line string) bool {
+	f, ok := message.ParseFooter(line)
+	return ok && f.Token == "BREAKING CHANGE" && strings.TrimSpace(f.Value) != "" &&
+		(strings.HasPrefix(line, "BREAKING CHANGE: ") || strings.HasPrefix(line, "BREAKING-CHANGE: "))
+}
+
+func hasCase(s, name string) bool {
+	letters := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, s)
+	if letters == "" {
+		return true
+	}
+	first := []rune(s)[0]
+
+	switch name {
+	case "lower-case", "lowercase":
+		return s == strings.ToLower(s)
+	case "upper-case", "uppercase":
+		return s == strings.ToUpper(s)
+	case "sentence-case", "sentencecase":
+		return unicode.IsUpper(first) && s[len(string(first)):] == strings.ToLower(s[len(string(first)):])
+	case "start-case", "startcase":
+		for _, word := range strings.Fields(s) {
+			if r := []rune(word)[0]; unicode.IsLetter(r) && !unicode.IsUpper(r) {
+				return false
+			}
+		}
+		return true
+	case "pascal-case", "pascalcase":
+		return unicode.IsUpper(first) && !strings.ContainsAny(s, " -_")
+	case "camel-case", "camelcase":
+		return unicode.IsLower(first) && !strings.ContainsAny(s, " -_")
+	case "kebab-case", "kebabcase":
+		return s == strings.ToLower(s) && !strings.ContainsAny(s, " _")
+	case "snake-case", "snakecase":
+		return s == strings.ToLower(s) && !strings.ContainsAny(s, " -")
+	}
+	return false
+}
+
+func splitScopes(scope string) []string {
+	return strings.FieldsFunc(scope, func(r rune) bool { return r == ',' || r == '/' || r == '\\' })
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func not(rule Rule) string {
+	if rule.Never {
+		return "not "
+	}
+	return ""
+}
@@ -0,0 +1,150 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		wantRules []string
+	}{
+		{
+			name: "should accept a conventional message",
+			text: "feat(api): add login endpoint\n\nAdds POST /login.\n\nRefs: PROJ-1",
+		},
+		{
+			name: "should accept a breaking change footer",
+			text: "refactor!: drop v1\n\nBREAKING CHANGE: v1 clients must upgrade",
+		},
+		{
+			name:      "should reject unknown type",
+			text:      "feature: add login",
+			wantRules: []string{"type-enum"},
+		},
+		{
+			name:      "should reject missing type",
+			text:      "add login",
+			wantRules: []string{"type-empty"},
+		},
+		{
+			name:      "should reject upper-case scope",
+			text:      "fix(API): handle timeouts",
+			wantRules: []string{"scope-case"},
+		},
+		{
+			name:      "should reject sentence-case subject with full stop",
+			text:      "fix: Handle timeouts.",
+			wantRules: []string{"subject-case", "subject-full-stop"},
+		},
+		{
+			name:      "should reject long header",
+			text:      "fix: " + strings.Repeat("x", 100),
+			wantRules: []string{"header-max-length"},
+		},
+		{
+			name:      "should warn about missing blank line before body",
+			text:      "fix: handle timeouts\nRetry once before failing.",
+			wantRules: []string{"body-leading-blank"},
+		},
+		{
+			name:      "should reject long body lines",
+			text:      "docs: explain config\n\n" + strings.Repeat("word ", 25),
+			wantRules: []string{"body-max-line-length"},
+		},
+		{
+			name:      "should reject malformed breaking change footer",
+			text:      "feat: new api\n\nbreaking change: old api removed",
+			wantRules: []string{"footer-breaking-change"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := DefaultRules().Validate(tt.text)
+
+			var got []string
+			for _, v := range result.Violations {
+				got = append(got, v.Rule)
+			}
+			if len(got) != len(tt.wantRules) {
+				t.Fatalf("Validate() violations = %v, want %v", result.Violations, tt.wantRules)
+			}
+			for i := range got {
+				if got[i] != tt.wantRules[i] {
+					t.Errorf("Validate() violations = %v, want %v", got, tt.wantRules)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		text     string
+		wantErr  bool
+		wantPass bool
+	}{
+		{
+			name:     "should use conventional defaults without config",
+			text:     "feat: add login",
+			wantPass: true,
+		},
+		{
+			name:     "should read YAML config extending conventional",
+			file:     ".commitlintrc.yml",
+			content:  "extends: ['@commitlint/config-conventional']\nrules:\n  type-enum: [2, always, [feat, fix, wip]]\n",
+			text:     "wip: half done",
+			wantPass: true,
+		},
+		{
+			name:     "should read JSON config",
+			file:     ".commitlintrc.json",
+			content:  `{"extends": ["@commitlint/config-conventional"], "rules": {"scope-enum": [2, "always", ["api", "web"]]}}`,
+			text:     "feat(cli): add flag",
+			wantPass: false,
+		},
+		{
+			name:     "should disable rules set to level 0",
+			file:     ".commitlintrc",
+			content:  "extends: '@commitlint/config-conventional'\nrules:\n  subject-case: [0]\n",
+			text:     "feat: Add login",
+			wantPass: true,
+		},
+		{
+			name:    "should report invalid levels",
+			file:    "commitlint.config.yaml",
+			content: "rules:\n  type-enum: [3, always, [feat]]\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.file != "" {
+				if err := os.WriteFile(filepath.Join(dir, tt.file), []byte(tt.content), 0644); err != nil {
+					t.Fatalf("Failed to write config: %v", err)
+				}
+			}
+
+			rules, _, err := LoadRules(dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := rules.Validate(tt.text).Valid(); got != tt.wantPass {
+				t.Errorf("Validate(%q).Valid() = %v, want %v", tt.text, got, tt.wantPass)
+			}
+		})
+	}
+}